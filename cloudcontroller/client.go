@@ -0,0 +1,199 @@
+package cloudcontroller
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+const (
+	apps          = "apps"
+	spaces        = "spaces"
+	organizations = "organizations"
+
+	// minRetryInterval is the time after which a failed fetch is first
+	// retried. It doubles with every further failure, up to the name TTL.
+	minRetryInterval = 10 * time.Second
+)
+
+// client resolves the names of Cloud Controller resources through the v3
+// API. Names are cached and fetched in the background, so lookups never
+// block on the Cloud Controller.
+type client struct {
+	url        string
+	tokens     oauth2.TokenSource
+	ttl        time.Duration
+	httpClient *http.Client
+
+	lock    sync.Mutex
+	names   map[string]cachedName
+	pending map[string]bool
+	authErr error
+}
+
+// authError is returned by fetches that failed because no token could be
+// obtained or the Cloud Controller rejected it.
+type authError struct {
+	error
+}
+
+// cachedName is the outcome of the last fetch of a name. Failed fetches are
+// cached as well, so that missing resources and rejected tokens do not cause
+// a request on every lookup. A failed fetch keeps the last known name.
+type cachedName struct {
+	name      string
+	found     bool
+	fetchedAt time.Time
+	failures  int
+}
+
+// expired returns whether a name should be fetched again.
+func (n cachedName) expired(ttl time.Duration) bool {
+	if n.failures == 0 {
+		return time.Since(n.fetchedAt) > ttl
+	}
+
+	retry := minRetryInterval << uint(n.failures-1)
+	if retry > ttl || retry <= 0 {
+		retry = ttl
+	}
+
+	return time.Since(n.fetchedAt) > retry
+}
+
+type resource struct {
+	GUID string `json:"guid"`
+	Name string `json:"name"`
+}
+
+// NewClient returns a client for the Cloud Controller at url that
+// authenticates with tokens from the given source, if any, see
+// ClientCredentials. Names are fetched again once they are older than ttl.
+// Failed fetches are retried with a backoff of at most ttl.
+func NewClient(url string, tokens oauth2.TokenSource, ttl time.Duration) *client {
+	return &client{
+		url:        strings.TrimRight(url, "/"),
+		tokens:     tokens,
+		ttl:        ttl,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		names:      map[string]cachedName{},
+		pending:    map[string]bool{},
+	}
+}
+
+// Err returns why the last fetch could not authenticate with the Cloud
+// Controller, or nil if it could.
+func (c *client) Err() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.authErr
+}
+
+func (c *client) AppName(guid string) (string, bool) {
+	return c.name(apps, guid)
+}
+
+func (c *client) SpaceName(guid string) (string, bool) {
+	return c.name(spaces, guid)
+}
+
+func (c *client) OrgName(guid string) (string, bool) {
+	return c.name(organizations, guid)
+}
+
+// name returns the cached name of a resource and fetches it in the
+// background if it is missing or has expired.
+func (c *client) name(kind, guid string) (string, bool) {
+	if guid == "" {
+		return "", false
+	}
+
+	key := fmt.Sprintf("%s/%s", kind, guid)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	cached, found := c.names[key]
+	if (!found || cached.expired(c.ttl)) && !c.pending[key] {
+		c.pending[key] = true
+		go c.refresh(kind, guid, key)
+	}
+
+	return cached.name, cached.found
+}
+
+func (c *client) refresh(kind, guid, key string) {
+	name, err := c.fetch(kind, guid)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	delete(c.pending, key)
+
+	switch err.(type) {
+	case nil:
+		c.authErr = nil
+	case authError:
+		c.authErr = err
+	}
+
+	if err != nil {
+		cached := c.names[key]
+		cached.fetchedAt = time.Now()
+		cached.failures++
+
+		// Only log the first of consecutive failures.
+		if cached.failures == 1 {
+			log.Println(err)
+		}
+
+		c.names[key] = cached
+		return
+	}
+
+	c.names[key] = cachedName{name: name, found: true, fetchedAt: time.Now()}
+}
+
+func (c *client) fetch(kind, guid string) (string, error) {
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s/v3/%s/%s", c.url, kind, guid), nil)
+	if err != nil {
+		return "", err
+	}
+
+	if c.tokens != nil {
+		token, err := c.tokens.Token()
+		if err != nil {
+			return "", authError{err}
+		}
+
+		token.SetAuthHeader(req)
+	}
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("error fetching %s %q from Cloud Controller: %v", kind, guid, err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusUnauthorized || res.StatusCode == http.StatusForbidden {
+		return "", authError{fmt.Errorf("error fetching %s %q from Cloud Controller: %s", kind, guid, res.Status)}
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("error fetching %s %q from Cloud Controller: %s", kind, guid, res.Status)
+	}
+
+	var r resource
+	if err := json.NewDecoder(res.Body).Decode(&r); err != nil {
+		return "", fmt.Errorf("error decoding %s %q from Cloud Controller: %v", kind, guid, err)
+	}
+
+	return r.Name, nil
+}
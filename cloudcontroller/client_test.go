@@ -0,0 +1,224 @@
+package cloudcontroller_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/st3v/scope-garden/cloudcontroller"
+	"golang.org/x/oauth2"
+)
+
+// fakeCloudController also acts as UAA. It only accepts the last token it
+// issued, or the token "secret" if it has not issued any.
+type fakeCloudController struct {
+	lock      sync.Mutex
+	url       string
+	names     map[string]string
+	requests  int
+	grants    int
+	token     string
+	expiresIn int
+}
+
+func (cc *fakeCloudController) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	cc.lock.Lock()
+	defer cc.lock.Unlock()
+
+	switch r.URL.Path {
+	case "/":
+		fmt.Fprintf(w, `{"links":{"uaa":{"href":%q}}}`, cc.url)
+		return
+	case "/oauth/token":
+		id, secret, _ := r.BasicAuth()
+		if id != "scope" || secret != "client-secret" || r.FormValue("grant_type") != "client_credentials" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		cc.grants++
+		cc.token = fmt.Sprintf("token-%d", cc.grants)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": cc.token,
+			"token_type":   "bearer",
+			"expires_in":   cc.expiresIn,
+		})
+		return
+	}
+
+	cc.requests++
+
+	if r.Header.Get("Authorization") != "Bearer "+cc.token {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	name, found := cc.names[strings.TrimPrefix(r.URL.Path, "/v3/")]
+	if !found {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"guid": r.URL.Path, "name": name})
+}
+
+func (cc *fakeCloudController) requestCount() int {
+	cc.lock.Lock()
+	defer cc.lock.Unlock()
+
+	return cc.requests
+}
+
+func (cc *fakeCloudController) rename(path, name string) {
+	cc.lock.Lock()
+	defer cc.lock.Unlock()
+
+	cc.names[path] = name
+}
+
+func (cc *fakeCloudController) grantCount() int {
+	cc.lock.Lock()
+	defer cc.lock.Unlock()
+
+	return cc.grants
+}
+
+func (cc *fakeCloudController) remove(path string) {
+	cc.lock.Lock()
+	defer cc.lock.Unlock()
+
+	delete(cc.names, path)
+}
+
+var _ = Describe("client", func() {
+	var (
+		cc     *fakeCloudController
+		server *httptest.Server
+	)
+
+	BeforeEach(func() {
+		cc = &fakeCloudController{
+			names: map[string]string{
+				"apps/app-guid":          "my-app",
+				"spaces/space-guid":      "my-space",
+				"organizations/org-guid": "my-org",
+			},
+			token:     "secret",
+			expiresIn: 3600,
+		}
+		server = httptest.NewServer(cc)
+		cc.url = server.URL
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	static := func(token string) oauth2.TokenSource {
+		return oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})
+	}
+
+	name := func(lookup func(string) (string, bool), guid string) func() string {
+		return func() string {
+			n, _ := lookup(guid)
+			return n
+		}
+	}
+
+	It("resolves names in the background", func() {
+		client := NewClient(server.URL, static("secret"), time.Minute)
+
+		_, found := client.AppName("app-guid")
+		Expect(found).To(BeFalse())
+
+		Eventually(name(client.AppName, "app-guid")).Should(Equal("my-app"))
+		Eventually(name(client.SpaceName, "space-guid")).Should(Equal("my-space"))
+		Eventually(name(client.OrgName, "org-guid")).Should(Equal("my-org"))
+	})
+
+	It("refreshes names once they expire", func() {
+		client := NewClient(server.URL, static("secret"), 10*time.Millisecond)
+
+		Eventually(name(client.AppName, "app-guid")).Should(Equal("my-app"))
+
+		cc.rename("apps/app-guid", "renamed-app")
+		Eventually(name(client.AppName, "app-guid")).Should(Equal("renamed-app"))
+	})
+
+	It("does not resolve names it cannot fetch", func() {
+		client := NewClient(server.URL, static("wrong"), time.Minute)
+
+		Consistently(name(client.AppName, "app-guid"), 100*time.Millisecond).Should(BeEmpty())
+	})
+
+	It("does not fetch names again right after a failure", func() {
+		client := NewClient(server.URL, static("secret"), time.Minute)
+
+		for i := 0; i < 10; i++ {
+			client.AppName("deleted-guid")
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		Expect(cc.requestCount()).To(Equal(1))
+	})
+
+	It("keeps the last known name when a refresh fails", func() {
+		client := NewClient(server.URL, static("secret"), 10*time.Millisecond)
+
+		Eventually(name(client.AppName, "app-guid")).Should(Equal("my-app"))
+
+		cc.remove("apps/app-guid")
+		Consistently(name(client.AppName, "app-guid"), 100*time.Millisecond).Should(Equal("my-app"))
+	})
+
+	Describe("with client credentials", func() {
+		It("gets tokens from the UAA the Cloud Controller links to", func() {
+			client := NewClient(server.URL, ClientCredentials(server.URL, "", "scope", "client-secret"), time.Minute)
+
+			Eventually(name(client.AppName, "app-guid")).Should(Equal("my-app"))
+			Expect(cc.grantCount()).To(Equal(1))
+			Expect(client.Err()).ToNot(HaveOccurred())
+		})
+
+		It("gets a new token once the current one expires", func() {
+			cc.expiresIn = 1
+			client := NewClient(server.URL, ClientCredentials(server.URL, server.URL, "scope", "client-secret"), 10*time.Millisecond)
+
+			Eventually(name(client.AppName, "app-guid")).Should(Equal("my-app"))
+
+			cc.rename("apps/app-guid", "renamed-app")
+			Eventually(name(client.AppName, "app-guid")).Should(Equal("renamed-app"))
+			Expect(cc.grantCount()).To(BeNumerically(">", 1))
+		})
+
+		It("reports rejected client credentials", func() {
+			client := NewClient(server.URL, ClientCredentials(server.URL, "", "scope", "wrong"), time.Minute)
+
+			client.AppName("app-guid")
+			Eventually(client.Err).Should(MatchError(ContainSubstring("401 Unauthorized")))
+			Expect(cc.requestCount()).To(BeZero())
+		})
+
+		It("reports rejected tokens until a fetch succeeds", func() {
+			client := NewClient(server.URL, static("wrong"), 10*time.Millisecond)
+
+			client.AppName("app-guid")
+			Eventually(client.Err).Should(MatchError(ContainSubstring("401 Unauthorized")))
+
+			cc.lock.Lock()
+			cc.token = "wrong"
+			cc.lock.Unlock()
+
+			Eventually(func() error {
+				client.AppName("app-guid")
+				return client.Err()
+			}).Should(Succeed())
+		})
+	})
+})
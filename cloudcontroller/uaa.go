@@ -0,0 +1,130 @@
+package cloudcontroller
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// uaaTokens gets Cloud Controller tokens from UAA with the client credentials
+// grant. The UAA URL is looked up from the Cloud Controller unless it is
+// given.
+type uaaTokens struct {
+	apiURL       string
+	clientID     string
+	clientSecret string
+	httpClient   *http.Client
+
+	lock   sync.Mutex
+	uaaURL string
+}
+
+type uaaToken struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+type rootLinks struct {
+	Links struct {
+		UAA struct {
+			Href string `json:"href"`
+		} `json:"uaa"`
+	} `json:"links"`
+}
+
+// ClientCredentials returns a token source for the Cloud Controller at apiURL
+// that authenticates as the given UAA client. Tokens are reused until they
+// are about to expire, at which point a new one is requested. If uaaURL is
+// empty, it is looked up from the Cloud Controller.
+func ClientCredentials(apiURL, uaaURL, clientID, clientSecret string) oauth2.TokenSource {
+	return oauth2.ReuseTokenSource(nil, &uaaTokens{
+		apiURL:       strings.TrimRight(apiURL, "/"),
+		uaaURL:       strings.TrimRight(uaaURL, "/"),
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		httpClient:   &http.Client{Timeout: 10 * time.Second},
+	})
+}
+
+func (t *uaaTokens) Token() (*oauth2.Token, error) {
+	uaaURL, err := t.uaa()
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{"grant_type": {"client_credentials"}}
+
+	req, err := http.NewRequest("POST", fmt.Sprintf("%s/oauth/token", uaaURL), strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+
+	req.SetBasicAuth(url.QueryEscape(t.clientID), url.QueryEscape(t.clientSecret))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	res, err := t.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error requesting token from UAA: %v", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error requesting token from UAA: %s", res.Status)
+	}
+
+	var token uaaToken
+	if err := json.NewDecoder(res.Body).Decode(&token); err != nil {
+		return nil, fmt.Errorf("error decoding token from UAA: %v", err)
+	}
+
+	if token.AccessToken == "" {
+		return nil, fmt.Errorf("error requesting token from UAA: no access token")
+	}
+
+	return &oauth2.Token{
+		AccessToken: token.AccessToken,
+		TokenType:   token.TokenType,
+		Expiry:      time.Now().Add(time.Duration(token.ExpiresIn) * time.Second),
+	}, nil
+}
+
+// uaa returns the URL of UAA, looking it up from the Cloud Controller's root
+// endpoint the first time if it was not given.
+func (t *uaaTokens) uaa() (string, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.uaaURL != "" {
+		return t.uaaURL, nil
+	}
+
+	res, err := t.httpClient.Get(fmt.Sprintf("%s/", t.apiURL))
+	if err != nil {
+		return "", fmt.Errorf("error looking up UAA from Cloud Controller: %v", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("error looking up UAA from Cloud Controller: %s", res.Status)
+	}
+
+	var root rootLinks
+	if err := json.NewDecoder(res.Body).Decode(&root); err != nil {
+		return "", fmt.Errorf("error decoding Cloud Controller links: %v", err)
+	}
+
+	if root.Links.UAA.Href == "" {
+		return "", fmt.Errorf("error looking up UAA from Cloud Controller: no uaa link")
+	}
+
+	t.uaaURL = strings.TrimRight(root.Links.UAA.Href, "/")
+	return t.uaaURL, nil
+}
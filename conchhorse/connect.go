@@ -3,44 +3,60 @@ package conchhorse
 import (
 	"context"
 	"crypto/tls"
-	"crypto/x509"
 	"net"
 	"net/http"
 	"time"
 
-	"github.com/concourse/fly/rc"
 	"github.com/concourse/go-concourse/concourse"
 	"golang.org/x/oauth2"
 )
 
-func NewClient(host, username, password string) (concourse.Client, error) {
-	target, err := rc.NewUnauthenticatedTarget(
-		"target",
-		host,
-		"main",
-		false,
-		"",
-		true)
+func NewClient(host string, credentials Credentials, tlsConfig TLSConfig) (concourse.Client, error) {
+	var (
+		caCert string
+		grant  grantFn
+	)
 
-	if err != nil {
-		return nil, err
+	if credentials.Source == CredentialsFlyrc {
+		target, err := flyTarget(credentials.FlyTarget)
+		if err != nil {
+			return nil, err
+		}
+
+		if host == "" {
+			host = target.API
+		}
+
+		caCert = target.CACert
+		tlsConfig.Insecure = tlsConfig.Insecure || target.Insecure
+		grant = staticGrant(target.Token.Type, target.Token.Value)
 	}
 
-	tokenType, tokenValue, err := passwordGrant(target.Client(), username, password)
+	config, err := tlsConfig.tlsConfig(caCert)
 	if err != nil {
 		return nil, err
 	}
 
-	token := &rc.TargetToken{
-		Type:  tokenType,
-		Value: tokenValue,
+	if grant == nil {
+		unauthenticated := concourse.NewClient(host, defaultHttpClient(nil, config), false)
+
+		grant, err = credentials.grant(unauthenticated)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	source := newTokenSource(grant)
+
+	if _, err := source.Token(); err != nil {
+		return nil, err
 	}
 
-	httpClient := defaultHttpClient(token, true, nil)
+	httpClient := defaultHttpClient(source, config)
 	return concourse.NewClient(host, httpClient, true), nil
 }
 
-func passwordGrant(client concourse.Client, username, password string) (string, string, error) {
+func passwordGrant(client concourse.Client, username, password string) (*oauth2.Token, error) {
 
 	oauth2Config := oauth2.Config{
 		ClientID:     "fly",
@@ -51,43 +67,27 @@ func passwordGrant(client concourse.Client, username, password string) (string,
 
 	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, client.HTTPClient())
 
-	token, err := oauth2Config.PasswordCredentialsToken(ctx, username, password)
-	if err != nil {
-		return "", "", err
-	}
-
-	return token.TokenType, token.AccessToken, nil
+	return oauth2Config.PasswordCredentialsToken(ctx, username, password)
 }
 
-func defaultHttpClient(token *rc.TargetToken, insecure bool, caCertPool *x509.CertPool) *http.Client {
-	var oAuthToken *oauth2.Token
-	if token != nil {
-		oAuthToken = &oauth2.Token{
-			TokenType:   token.Type,
-			AccessToken: token.Value,
-		}
-	}
-
-	transport := transport(insecure, caCertPool)
+func defaultHttpClient(source *tokenSource, tlsConfig *tls.Config) *http.Client {
+	transport := transport(tlsConfig)
 
-	if token != nil {
-		transport = &oauth2.Transport{
-			Source: oauth2.StaticTokenSource(oAuthToken),
-			Base:   transport,
+	if source != nil {
+		transport = &reauthTransport{
+			source: source,
+			base:   transport,
 		}
 	}
 
 	return &http.Client{Transport: transport}
 }
 
-func transport(insecure bool, caCertPool *x509.CertPool) http.RoundTripper {
+func transport(tlsConfig *tls.Config) http.RoundTripper {
 	var transport http.RoundTripper
 
 	transport = &http.Transport{
-		TLSClientConfig: &tls.Config{
-			InsecureSkipVerify: insecure,
-			RootCAs:            caCertPool,
-		},
+		TLSClientConfig: tlsConfig,
 		Dial: (&net.Dialer{
 			Timeout: 10 * time.Second,
 		}).Dial,
@@ -9,7 +9,7 @@ import (
 var _ = Describe("atc", func() {
 	Describe("connecting", func() {
 		It("should jolly-well work", func() {
-			atcClient, err := NewClient("http://10.244.15.2:8080", "admin", "admin")
+			atcClient, err := NewClient("http://10.244.15.2:8080", Credentials{Username: "admin", Password: "admin"}, TLSConfig{})
 			Expect(err).ToNot(HaveOccurred())
 
 			atcInfo, err := atcClient.GetInfo()
@@ -1,7 +1,10 @@
 package conchhorse
 
 import (
+	"errors"
+	"fmt"
 	"log"
+	"sort"
 	"sync"
 	"time"
 
@@ -12,14 +15,41 @@ import (
 type directory struct {
 	lock       sync.RWMutex
 	done       chan struct{}
-	containers map[string]atc.Container
+	containers map[string]teamContainer
+	workers    []atc.Worker
+	volumes    map[string][]atc.Volume
+	err        error
+	auth       *tokenSource
 	client     concourse.Client
+	connect    connectFn
+	teams      []string
 }
 
-func NewAppDirectory(client concourse.Client, fetchInterval time.Duration) *directory {
+type connectFn func() (concourse.Client, error)
+
+type teamContainer struct {
+	container atc.Container
+	team      string
+}
+
+// NewAppDirectory returns a directory of the containers of the given teams.
+// If no teams are given, the containers of all teams known to the ATC are
+// fetched.
+func NewAppDirectory(client concourse.Client, fetchInterval time.Duration, teams []string) *directory {
+	return ConnectAppDirectory(func() (concourse.Client, error) {
+		return client, nil
+	}, fetchInterval, teams)
+}
+
+// ConnectAppDirectory returns a directory like NewAppDirectory that connects
+// to the ATC itself. Until connect succeeds, it is retried on every fetch and
+// Err reports why the ATC is not available.
+func ConnectAppDirectory(connect func() (concourse.Client, error), fetchInterval time.Duration, teams []string) *directory {
 	d := &directory{
-		client: client,
-		done:   make(chan struct{}),
+		connect: connect,
+		teams:   teams,
+		done:    make(chan struct{}),
+		err:     errors.New("not connected to ATC yet"),
 	}
 
 	d.fetch(fetchInterval)
@@ -34,26 +64,119 @@ func (d *directory) Close() {
 	close(d.done)
 }
 
-func (d *directory) ConcourseContainer(guid string) (atc.Container, bool) {
+func (d *directory) ConcourseContainer(guid string) (atc.Container, string, bool) {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+
+	if c, found := d.containers[guid]; found {
+		return c.container, c.team, true
+	}
+
+	return atc.Container{}, "", false
+}
+
+// Err returns the error of the last authentication with the ATC or else
+// of the last fetch from it, if any.
+func (d *directory) Err() error {
 	d.lock.RLock()
 	defer d.lock.RUnlock()
 
-	if container, found := d.containers[guid]; found {
-		return container, true
+	if d.auth != nil {
+		if err := d.auth.Err(); err != nil {
+			return err
+		}
 	}
 
-	return atc.Container{}, false
+	return d.err
+}
+
+func (d *directory) setErr(err error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.err = err
 }
 
-func (d *directory) set(containers []atc.Container) {
+func (d *directory) Workers() []atc.Worker {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+
+	return d.workers
+}
+
+func (d *directory) setWorkers(workers []atc.Worker) {
 	d.lock.Lock()
 	defer d.lock.Unlock()
 
-	d.containers = map[string]atc.Container{}
+	d.workers = workers
+}
+
+func (d *directory) Volumes() []atc.Volume {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+
+	teams := make([]string, 0, len(d.volumes))
+	for team := range d.volumes {
+		teams = append(teams, team)
+	}
+	sort.Strings(teams)
 
-	for _, container := range containers {
-		d.containers[container.ID] = container
+	var volumes []atc.Volume
+	for _, team := range teams {
+		volumes = append(volumes, d.volumes[team]...)
 	}
+
+	return volumes
+}
+
+func (d *directory) setVolumes(volumes map[string][]atc.Volume) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.volumes = volumes
+}
+
+func (d *directory) set(containers map[string]teamContainer) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.containers = containers
+}
+
+// dial connects to the ATC unless the directory is already connected. It is
+// only called from the fetch loop, which owns d.client.
+func (d *directory) dial() error {
+	if d.client != nil {
+		return nil
+	}
+
+	client, err := d.connect()
+	if err != nil {
+		return fmt.Errorf("error connecting to ATC: %v", err)
+	}
+
+	d.client = client
+
+	d.lock.Lock()
+	d.auth = authSource(client)
+	d.lock.Unlock()
+
+	return nil
+}
+
+// authSource returns the token source of a client created by NewClient, or
+// nil if the client does not authenticate with one.
+func authSource(client concourse.Client) *tokenSource {
+	httpClient := client.HTTPClient()
+	if httpClient == nil {
+		return nil
+	}
+
+	if t, ok := httpClient.Transport.(*reauthTransport); ok {
+		return t.source
+	}
+
+	return nil
 }
 
 func (d *directory) fetch(interval time.Duration) {
@@ -61,17 +184,118 @@ func (d *directory) fetch(interval time.Duration) {
 		for {
 			select {
 			case <-time.After(interval):
-				team := d.client.Team("main")
-				containers, err := team.ListContainers(map[string]string{})
+				if err := d.dial(); err != nil {
+					d.setErr(err)
+					log.Println(err)
+					continue
+				}
+
+				workers, workersErr := d.client.ListWorkers()
+				if workersErr != nil {
+					workersErr = fmt.Errorf("error fetching Concourse workers: %v", workersErr)
+					log.Println(workersErr)
+				} else {
+					d.setWorkers(workers)
+				}
+
+				teams, err := d.teamNames()
 				if err != nil {
-					log.Printf("error fetching Concourse containers: %v\n", err)
+					d.setErr(err)
+					log.Println(err)
 					continue
 				}
 
+				containers, volumes, err := d.fetchTeams(teams)
+				if err == nil {
+					err = workersErr
+				}
+				d.setErr(err)
+
 				d.set(containers)
+				d.setVolumes(volumes)
 			case <-d.done:
 				return
 			}
 		}
 	}()
 }
+
+func (d *directory) teamNames() ([]string, error) {
+	if len(d.teams) > 0 {
+		return d.teams, nil
+	}
+
+	teams, err := d.client.ListTeams()
+	if err != nil {
+		return nil, fmt.Errorf("error fetching Concourse teams: %v", err)
+	}
+
+	names := make([]string, len(teams))
+	for i, t := range teams {
+		names[i] = t.Name
+	}
+
+	return names, nil
+}
+
+// fetchTeams merges the containers and volumes of all given teams, by team.
+// Teams that fail keep their previously fetched containers and volumes, and
+// the last error is returned alongside the rest.
+func (d *directory) fetchTeams(teams []string) (map[string]teamContainer, map[string][]atc.Volume, error) {
+	var (
+		lastErr    error
+		volumes    = map[string][]atc.Volume{}
+		containers = map[string]teamContainer{}
+	)
+
+	for _, name := range teams {
+		team := d.client.Team(name)
+
+		teamVolumes, err := team.ListVolumes()
+		if err != nil {
+			lastErr = fmt.Errorf("error fetching Concourse volumes for team %q: %v", name, err)
+			log.Println(lastErr)
+			teamVolumes = d.previousVolumes(name)
+		}
+		volumes[name] = teamVolumes
+
+		teamContainers, err := team.ListContainers(map[string]string{})
+		if err != nil {
+			lastErr = fmt.Errorf("error fetching Concourse containers for team %q: %v", name, err)
+			log.Println(lastErr)
+			for id, c := range d.previousContainers(name) {
+				containers[id] = c
+			}
+			continue
+		}
+
+		for _, c := range teamContainers {
+			containers[c.ID] = teamContainer{container: c, team: name}
+		}
+	}
+
+	return containers, volumes, lastErr
+}
+
+// previousContainers returns the last fetched containers of a team.
+func (d *directory) previousContainers(team string) map[string]teamContainer {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+
+	containers := map[string]teamContainer{}
+	for id, c := range d.containers {
+		if c.team == team {
+			containers[id] = c
+		}
+	}
+
+	return containers
+}
+
+// previousVolumes returns the last fetched volumes of a team.
+func (d *directory) previousVolumes(team string) []atc.Volume {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+
+	return d.volumes[team]
+}
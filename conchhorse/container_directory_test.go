@@ -0,0 +1,111 @@
+package conchhorse_test
+
+import (
+	"errors"
+	"net/http/httptest"
+	"time"
+
+	"github.com/concourse/atc"
+	"github.com/concourse/go-concourse/concourse"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/st3v/scope-garden/conchhorse"
+)
+
+var _ = Describe("directory", func() {
+	var (
+		fake   *fakeATC
+		server *httptest.Server
+	)
+
+	BeforeEach(func() {
+		fake = &fakeATC{}
+		server = httptest.NewServer(fake)
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	lookup := func(dir interface {
+		ConcourseContainer(string) (atc.Container, string, bool)
+	}, handle string) func() string {
+		return func() string {
+			_, team, _ := dir.ConcourseContainer(handle)
+			return team
+		}
+	}
+
+	It("merges the containers of all teams", func() {
+		client, err := NewClient(server.URL, Credentials{Username: "admin", Password: "secret"}, TLSConfig{})
+		Expect(err).ToNot(HaveOccurred())
+
+		dir := NewAppDirectory(client, 10*time.Millisecond, nil)
+		defer dir.Close()
+
+		Eventually(lookup(dir, "main-handle")).Should(Equal("main"))
+		Eventually(lookup(dir, "other-handle")).Should(Equal("other"))
+	})
+
+	It("only fetches the configured teams", func() {
+		client, err := NewClient(server.URL, Credentials{Username: "admin", Password: "secret"}, TLSConfig{})
+		Expect(err).ToNot(HaveOccurred())
+
+		dir := NewAppDirectory(client, 10*time.Millisecond, []string{"other"})
+		defer dir.Close()
+
+		Eventually(lookup(dir, "other-handle")).Should(Equal("other"))
+		Consistently(lookup(dir, "main-handle"), 100*time.Millisecond).Should(BeEmpty())
+	})
+
+	It("keeps retrying to connect to the ATC", func() {
+		attempts := 0
+		connect := func() (concourse.Client, error) {
+			attempts++
+			if attempts < 3 {
+				return nil, errors.New("connection refused")
+			}
+			return NewClient(server.URL, Credentials{Username: "admin", Password: "secret"}, TLSConfig{})
+		}
+
+		dir := ConnectAppDirectory(connect, 10*time.Millisecond, nil)
+		defer dir.Close()
+
+		Expect(dir.Err()).To(HaveOccurred())
+		Eventually(lookup(dir, "main-handle")).Should(Equal("main"))
+		Eventually(dir.Err).ShouldNot(HaveOccurred())
+	})
+
+	It("reports authentication failures", func() {
+		client, err := NewClient(server.URL, Credentials{Username: "admin", Password: "secret"}, TLSConfig{})
+		Expect(err).ToNot(HaveOccurred())
+
+		dir := NewAppDirectory(client, 10*time.Millisecond, nil)
+		defer dir.Close()
+
+		Eventually(lookup(dir, "main-handle")).Should(Equal("main"))
+		Expect(dir.Err()).ToNot(HaveOccurred())
+
+		fake.rejectCredentials()
+
+		Eventually(dir.Err).Should(MatchError(ContainSubstring("error authenticating with ATC")))
+	})
+
+	It("keeps the containers and volumes of teams that fail", func() {
+		client, err := NewClient(server.URL, Credentials{Username: "admin", Password: "secret"}, TLSConfig{})
+		Expect(err).ToNot(HaveOccurred())
+
+		dir := NewAppDirectory(client, 10*time.Millisecond, nil)
+		defer dir.Close()
+
+		Eventually(lookup(dir, "other-handle")).Should(Equal("other"))
+		Eventually(dir.Volumes).Should(HaveLen(1))
+
+		fake.failTeam("other")
+
+		Eventually(dir.Err).Should(MatchError(ContainSubstring(`for team "other"`)))
+		Consistently(lookup(dir, "other-handle"), 100*time.Millisecond).Should(Equal("other"))
+		Expect(lookup(dir, "main-handle")()).To(Equal("main"))
+		Expect(dir.Volumes()).To(HaveLen(1))
+	})
+})
@@ -0,0 +1,128 @@
+package conchhorse
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/concourse/fly/rc"
+	"github.com/concourse/go-concourse/concourse"
+	"golang.org/x/oauth2"
+)
+
+const (
+	CredentialsPassword     = "password"
+	CredentialsPasswordFile = "password-file"
+	CredentialsToken        = "token"
+	CredentialsFlyrc        = "flyrc"
+)
+
+// Credentials selects how the plugin authenticates with the ATC.
+type Credentials struct {
+	// Source is one of the Credentials* constants. It defaults to
+	// CredentialsPassword.
+	Source string
+
+	Username string
+	Password string
+
+	// PasswordFile is read on every authentication, so that a rotated
+	// secret is picked up without a restart.
+	PasswordFile string
+
+	// Token is a pre-issued bearer token.
+	Token string
+
+	// FlyTarget is the name of a target in ~/.flyrc whose URL, token and
+	// TLS settings are used.
+	FlyTarget string
+}
+
+// Validate checks that the credentials are complete without contacting the
+// ATC.
+func (c Credentials) Validate() error {
+	switch c.Source {
+	case "", CredentialsPassword:
+		if c.Username == "" {
+			return fmt.Errorf("no username configured")
+		}
+	case CredentialsPasswordFile:
+		if c.Username == "" {
+			return fmt.Errorf("no username configured")
+		}
+		if c.PasswordFile == "" {
+			return fmt.Errorf("no password file configured")
+		}
+	case CredentialsToken:
+		if c.Token == "" {
+			return fmt.Errorf("no token configured")
+		}
+	case CredentialsFlyrc:
+		if c.FlyTarget == "" {
+			return fmt.Errorf("no fly target configured")
+		}
+	default:
+		return fmt.Errorf("unknown credentials source %q", c.Source)
+	}
+
+	return nil
+}
+
+// grant returns a function that obtains a token for the configured source.
+func (c Credentials) grant(client concourse.Client) (grantFn, error) {
+	switch c.Source {
+	case "", CredentialsPassword:
+		return func() (*oauth2.Token, error) {
+			return passwordGrant(client, c.Username, c.Password)
+		}, nil
+	case CredentialsPasswordFile:
+		if c.PasswordFile == "" {
+			return nil, fmt.Errorf("no password file configured")
+		}
+
+		return func() (*oauth2.Token, error) {
+			password, err := ioutil.ReadFile(c.PasswordFile)
+			if err != nil {
+				return nil, fmt.Errorf("error reading password file %q: %v", c.PasswordFile, err)
+			}
+
+			return passwordGrant(client, c.Username, strings.TrimSpace(string(password)))
+		}, nil
+	case CredentialsToken:
+		if c.Token == "" {
+			return nil, fmt.Errorf("no token configured")
+		}
+
+		return staticGrant("Bearer", c.Token), nil
+	default:
+		return nil, fmt.Errorf("unknown credentials source %q", c.Source)
+	}
+}
+
+func staticGrant(tokenType, value string) grantFn {
+	return func() (*oauth2.Token, error) {
+		return &oauth2.Token{
+			TokenType:   tokenType,
+			AccessToken: value,
+		}, nil
+	}
+}
+
+// flyTarget loads the named target from ~/.flyrc.
+func flyTarget(name string) (rc.TargetProps, error) {
+	targets, err := rc.LoadTargets()
+	if err != nil {
+		return rc.TargetProps{}, fmt.Errorf("error loading fly targets: %v", err)
+	}
+
+	target, found := targets.Targets[rc.TargetName(name)]
+	if !found {
+		return rc.TargetProps{}, rc.UnknownTargetError{TargetName: rc.TargetName(name)}
+	}
+
+	if target.Token == nil || target.Token.Value == "" {
+		return rc.TargetProps{}, fmt.Errorf("fly target %q has no token, run fly login first", name)
+	}
+
+	return target, nil
+}
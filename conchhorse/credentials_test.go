@@ -0,0 +1,129 @@
+package conchhorse_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/st3v/scope-garden/conchhorse"
+)
+
+var _ = Describe("credentials", func() {
+	var (
+		atc    *fakeATC
+		server *httptest.Server
+		dir    string
+	)
+
+	BeforeEach(func() {
+		atc = &fakeATC{}
+		server = httptest.NewServer(atc)
+
+		var err error
+		dir, err = ioutil.TempDir("", "conchhorse-credentials")
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		server.Close()
+		os.RemoveAll(dir)
+	})
+
+	It("reads the password from a file on every authentication", func() {
+		passwordFile := filepath.Join(dir, "password")
+		Expect(ioutil.WriteFile(passwordFile, []byte("secret\n"), 0600)).To(Succeed())
+
+		client, err := NewClient(server.URL, Credentials{
+			Source:       CredentialsPasswordFile,
+			Username:     "admin",
+			PasswordFile: passwordFile,
+		}, TLSConfig{})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(os.Remove(passwordFile)).To(Succeed())
+		atc.revoke()
+
+		_, err = client.ListWorkers()
+		Expect(err).To(MatchError(ContainSubstring("error reading password file")))
+	})
+
+	It("uses a pre-issued bearer token", func() {
+		atc.valid = "pre-issued"
+
+		client, err := NewClient(server.URL, Credentials{
+			Source: CredentialsToken,
+			Token:  "pre-issued",
+		}, TLSConfig{})
+		Expect(err).ToNot(HaveOccurred())
+
+		workers, err := client.ListWorkers()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(workers).To(HaveLen(1))
+		Expect(atc.grants).To(BeZero())
+	})
+
+	Context("with a fly target", func() {
+		var home string
+
+		BeforeEach(func() {
+			home = os.Getenv("HOME")
+			os.Setenv("HOME", dir)
+
+			flyrc := fmt.Sprintf(`targets:
+  ci:
+    api: %s
+    team: main
+    token:
+      type: bearer
+      value: from-flyrc
+`, server.URL)
+			Expect(ioutil.WriteFile(filepath.Join(dir, ".flyrc"), []byte(flyrc), 0600)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			os.Setenv("HOME", home)
+		})
+
+		It("connects to the target's ATC with its token", func() {
+			atc.valid = "from-flyrc"
+
+			client, err := NewClient("", Credentials{
+				Source:    CredentialsFlyrc,
+				FlyTarget: "ci",
+			}, TLSConfig{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(client.URL()).To(Equal(server.URL))
+
+			workers, err := client.ListWorkers()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(workers).To(HaveLen(1))
+		})
+
+		It("fails for an unknown target", func() {
+			_, err := NewClient("", Credentials{
+				Source:    CredentialsFlyrc,
+				FlyTarget: "missing",
+			}, TLSConfig{})
+			Expect(err).To(MatchError("unknown target: missing"))
+		})
+	})
+
+	It("rejects unknown sources", func() {
+		_, err := NewClient(server.URL, Credentials{Source: "magic"}, TLSConfig{})
+		Expect(err).To(MatchError(`unknown credentials source "magic"`))
+	})
+
+	It("validates credentials without contacting the ATC", func() {
+		Expect(Credentials{Username: "admin"}.Validate()).To(Succeed())
+		Expect(Credentials{Source: CredentialsToken, Token: "t"}.Validate()).To(Succeed())
+
+		Expect(Credentials{}.Validate()).To(MatchError("no username configured"))
+		Expect(Credentials{Source: CredentialsPasswordFile, Username: "admin"}.Validate()).To(MatchError("no password file configured"))
+		Expect(Credentials{Source: CredentialsFlyrc}.Validate()).To(MatchError("no fly target configured"))
+		Expect(Credentials{Source: "kerberos"}.Validate()).To(MatchError(`unknown credentials source "kerberos"`))
+	})
+})
@@ -0,0 +1,71 @@
+package conchhorse
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+)
+
+// TLSConfig configures how connections to the ATC are secured.
+type TLSConfig struct {
+	// CACertFile is a PEM bundle of CAs trusted in addition to the system's.
+	CACertFile string
+
+	// ClientCertFile and ClientKeyFile are a PEM certificate and key
+	// presented to the ATC for mutual TLS.
+	ClientCertFile string
+	ClientKeyFile  string
+
+	// Insecure skips verification of the ATC's certificate.
+	Insecure bool
+}
+
+// tlsConfig builds the TLS configuration. caCert is an optional PEM bundle
+// of further CAs to trust, such as the CA stored with a fly target.
+func (c TLSConfig) tlsConfig(caCert string) (*tls.Config, error) {
+	config := &tls.Config{
+		InsecureSkipVerify: c.Insecure,
+	}
+
+	if c.CACertFile != "" || caCert != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+
+		if c.CACertFile != "" {
+			pem, err := ioutil.ReadFile(c.CACertFile)
+			if err != nil {
+				return nil, fmt.Errorf("error reading CA certificate %q: %v", c.CACertFile, err)
+			}
+
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("no certificates found in %q", c.CACertFile)
+			}
+		}
+
+		if caCert != "" && !pool.AppendCertsFromPEM([]byte(caCert)) {
+			return nil, fmt.Errorf("no certificates found in fly target CA")
+		}
+
+		config.RootCAs = pool
+	}
+
+	if c.ClientCertFile != "" || c.ClientKeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.ClientCertFile, c.ClientKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("error loading client certificate: %v", err)
+		}
+
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	return config, nil
+}
+
+// Validate checks that the configured certificates can be loaded.
+func (c TLSConfig) Validate() error {
+	_, err := c.tlsConfig("")
+	return err
+}
@@ -0,0 +1,89 @@
+package conchhorse_test
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/st3v/scope-garden/conchhorse"
+)
+
+var _ = Describe("TLS", func() {
+	var (
+		server *httptest.Server
+		dir    string
+		caCert string
+	)
+
+	writePEM := func(name, blockType string, der []byte) string {
+		path := filepath.Join(dir, name)
+		Expect(ioutil.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0600)).To(Succeed())
+		return path
+	}
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "conchhorse-tls")
+		Expect(err).ToNot(HaveOccurred())
+
+		server = httptest.NewUnstartedServer(&fakeATC{})
+		server.StartTLS()
+
+		caCert = writePEM("ca.pem", "CERTIFICATE", server.Certificate().Raw)
+	})
+
+	AfterEach(func() {
+		server.Close()
+		os.RemoveAll(dir)
+	})
+
+	It("verifies the ATC's certificate", func() {
+		_, err := NewClient(server.URL, Credentials{Username: "admin", Password: "secret"}, TLSConfig{})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("trusts a configured CA", func() {
+		_, err := NewClient(server.URL, Credentials{Username: "admin", Password: "secret"}, TLSConfig{CACertFile: caCert})
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("skips verification if insecure", func() {
+		_, err := NewClient(server.URL, Credentials{Username: "admin", Password: "secret"}, TLSConfig{Insecure: true})
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("presents a client certificate", func() {
+		server.Close()
+		server = httptest.NewUnstartedServer(&fakeATC{})
+		server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+		server.StartTLS()
+
+		cert := server.TLS.Certificates[0]
+		key, err := x509.MarshalPKCS8PrivateKey(cert.PrivateKey)
+		Expect(err).ToNot(HaveOccurred())
+
+		clientCert := writePEM("client.pem", "CERTIFICATE", cert.Certificate[0])
+		clientKey := writePEM("client-key.pem", "PRIVATE KEY", key)
+
+		_, err = NewClient(server.URL, Credentials{Username: "admin", Password: "secret"}, TLSConfig{Insecure: true})
+		Expect(err).To(HaveOccurred())
+
+		_, err = NewClient(server.URL, Credentials{Username: "admin", Password: "secret"}, TLSConfig{
+			Insecure:       true,
+			ClientCertFile: clientCert,
+			ClientKeyFile:  clientKey,
+		})
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("fails on an unreadable CA bundle", func() {
+		_, err := NewClient(server.URL, Credentials{Username: "admin", Password: "secret"}, TLSConfig{CACertFile: filepath.Join(dir, "missing.pem")})
+		Expect(err).To(MatchError(ContainSubstring("error reading CA certificate")))
+	})
+})
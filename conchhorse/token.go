@@ -0,0 +1,117 @@
+package conchhorse
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+
+	"golang.org/x/oauth2"
+)
+
+type grantFn func() (*oauth2.Token, error)
+
+// tokenSource caches an ATC token and re-authenticates once it has expired
+// or has been invalidated after the ATC rejected it.
+type tokenSource struct {
+	lock  sync.Mutex
+	grant grantFn
+	token *oauth2.Token
+	err   error
+}
+
+func newTokenSource(grant grantFn) *tokenSource {
+	return &tokenSource{grant: grant}
+}
+
+func (s *tokenSource) Token() (*oauth2.Token, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.token.Valid() {
+		return s.token, nil
+	}
+
+	token, err := s.grant()
+	if err != nil {
+		s.token = nil
+		s.err = fmt.Errorf("error authenticating with ATC: %v", err)
+		log.Println(s.err)
+		return nil, s.err
+	}
+
+	s.token = token
+	s.err = nil
+
+	return token, nil
+}
+
+// invalidate drops the cached token if it is still the given one, so that
+// the next call to Token re-authenticates.
+func (s *tokenSource) invalidate(token *oauth2.Token) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.token == token {
+		s.token = nil
+	}
+}
+
+// Err returns the error of the last authentication attempt, if any.
+func (s *tokenSource) Err() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.err
+}
+
+// reauthTransport authorizes requests with a token from its source and
+// retries a request once with a fresh token if the ATC responds with 401.
+type reauthTransport struct {
+	source *tokenSource
+	base   http.RoundTripper
+}
+
+func (t *reauthTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	res, token, err := t.roundTrip(req)
+	if err != nil || res.StatusCode != http.StatusUnauthorized || !rewindable(req) {
+		return res, err
+	}
+
+	log.Println("ATC rejected token, re-authenticating")
+	t.source.invalidate(token)
+	res.Body.Close()
+
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		req = req.WithContext(req.Context())
+		req.Body = body
+	}
+
+	res, _, err = t.roundTrip(req)
+	return res, err
+}
+
+func (t *reauthTransport) roundTrip(req *http.Request) (*http.Response, *oauth2.Token, error) {
+	token, err := t.source.Token()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	r := req.WithContext(req.Context())
+	r.Header = make(http.Header, len(req.Header))
+	for k, v := range req.Header {
+		r.Header[k] = v
+	}
+	token.SetAuthHeader(r)
+
+	res, err := t.base.RoundTrip(r)
+	return res, token, err
+}
+
+func rewindable(req *http.Request) bool {
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
@@ -0,0 +1,135 @@
+package conchhorse_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/st3v/scope-garden/conchhorse"
+)
+
+type fakeATC struct {
+	lock     sync.Mutex
+	grants   int
+	valid    string
+	rejected bool
+	failing  string
+}
+
+func (a *fakeATC) revoke() {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	a.valid = ""
+}
+
+// failTeam makes the container and volume requests of a team fail.
+func (a *fakeATC) failTeam(team string) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	a.failing = team
+}
+
+// rejectCredentials revokes the current token and rejects all further
+// password grants.
+func (a *fakeATC) rejectCredentials() {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	a.valid = ""
+	a.rejected = true
+}
+
+func (a *fakeATC) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	if a.failing != "" && strings.HasPrefix(r.URL.Path, "/api/v1/teams/"+a.failing+"/") {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	switch r.URL.Path {
+	case "/sky/token":
+		if r.FormValue("password") != "secret" || a.rejected {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusUnauthorized)
+			return
+		}
+
+		a.grants++
+		a.valid = fmt.Sprintf("token-%d", a.grants)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": a.valid,
+			"token_type":   "bearer",
+			"expires_in":   3600,
+		})
+	case "/api/v1/workers":
+		if r.Header.Get("Authorization") != "Bearer "+a.valid {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[{"name":"worker"}]`))
+	case "/api/v1/teams":
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[{"name":"main"},{"name":"other"}]`))
+	case "/api/v1/teams/main/containers":
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[{"id":"main-handle","pipeline_name":"main-pipeline"}]`))
+	case "/api/v1/teams/other/containers":
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[{"id":"other-handle","pipeline_name":"other-pipeline"}]`))
+	case "/api/v1/teams/main/volumes":
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[]`))
+	case "/api/v1/teams/other/volumes":
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[{"id":"other-volume","pipeline_name":"other-pipeline"}]`))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+var _ = Describe("token refresh", func() {
+	var (
+		atc    *fakeATC
+		server *httptest.Server
+	)
+
+	BeforeEach(func() {
+		atc = &fakeATC{}
+		server = httptest.NewServer(atc)
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("re-authenticates when the ATC rejects the token", func() {
+		client, err := NewClient(server.URL, Credentials{Username: "admin", Password: "secret"}, TLSConfig{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(atc.grants).To(Equal(1))
+
+		atc.revoke()
+
+		workers, err := client.ListWorkers()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(workers).To(HaveLen(1))
+		Expect(atc.grants).To(Equal(2))
+	})
+
+	It("fails when the credentials are rejected", func() {
+		_, err := NewClient(server.URL, Credentials{Username: "admin", Password: "wrong"}, TLSConfig{})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("error authenticating with ATC"))
+	})
+})
@@ -0,0 +1,318 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"os"
+	"runtime"
+	"time"
+
+	"github.com/st3v/scope-garden/conchhorse"
+	"github.com/st3v/scope-garden/garden"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// config holds every option of the plugin. It is initialised from flags and
+// environment variables, which the config file, if any, overrides. Durations
+// in the file are written like flags, e.g. 3s. Reloading applies everything
+// but the plugins root, which only takes effect on restart.
+type config struct {
+	Hostname    string        `yaml:"hostname"`
+	PluginsRoot string        `yaml:"plugins_root"`
+	Garden      gardenConfig  `yaml:"garden"`
+	Scope       scopeConfig   `yaml:"scope"`
+	ATC         atcConfig     `yaml:"atc"`
+	CF          cfConfig      `yaml:"cf"`
+	Metrics     metricsConfig `yaml:"metrics"`
+}
+
+type gardenConfig struct {
+	Network          string           `yaml:"network"`
+	Addr             string           `yaml:"addr"`
+	RefreshInterval  time.Duration    `yaml:"refresh_interval"`
+	RefreshTimeout   time.Duration    `yaml:"refresh_timeout"`
+	ContainerTimeout time.Duration    `yaml:"container_timeout"`
+	Workers          int              `yaml:"workers"`
+	NormalizeCPU     bool             `yaml:"normalize_cpu"`
+	DepotDir         string           `yaml:"depot_dir"`
+	CgroupRoot       string           `yaml:"cgroup_root"`
+	Properties       propertiesConfig `yaml:"properties"`
+}
+
+type propertiesConfig struct {
+	Allow     []string `yaml:"allow"`
+	Deny      []string `yaml:"deny"`
+	Redact    []string `yaml:"redact"`
+	MaxLength int      `yaml:"max_length"`
+}
+
+type scopeConfig struct {
+	AppURL string `yaml:"app_url"`
+	Token  string `yaml:"token"`
+}
+
+type atcConfig struct {
+	URL             string        `yaml:"url"`
+	Auth            string        `yaml:"auth"`
+	Username        string        `yaml:"username"`
+	Password        string        `yaml:"password"`
+	PasswordFile    string        `yaml:"password_file"`
+	Token           string        `yaml:"token"`
+	FlyTarget       string        `yaml:"fly_target"`
+	Teams           []string      `yaml:"teams"`
+	RefreshInterval time.Duration `yaml:"refresh_interval"`
+	CACert          string        `yaml:"ca_cert"`
+	ClientCert      string        `yaml:"client_cert"`
+	ClientKey       string        `yaml:"client_key"`
+	Insecure        bool          `yaml:"insecure"`
+}
+
+type cfConfig struct {
+	Enabled      bool          `yaml:"enabled"`
+	APIURL       string        `yaml:"api_url"`
+	UAAURL       string        `yaml:"uaa_url"`
+	ClientID     string        `yaml:"client_id"`
+	ClientSecret string        `yaml:"client_secret"`
+	NameTTL      time.Duration `yaml:"name_ttl"`
+}
+
+type metricsConfig struct {
+	Addr string `yaml:"addr"`
+}
+
+func configFromFlags() config {
+	return config{
+		Hostname:    hostname,
+		PluginsRoot: pluginsRoot,
+		Garden: gardenConfig{
+			Network:          gardenNetwork,
+			Addr:             gardenAddr,
+			RefreshInterval:  gardenRefreshInterval,
+			RefreshTimeout:   gardenRefreshTimeout,
+			ContainerTimeout: containerTimeout,
+			Workers:          collectionWorkers,
+			NormalizeCPU:     normalizeCPU,
+			DepotDir:         gardenDepotDir,
+			CgroupRoot:       gardenCgroupRoot,
+			Properties: propertiesConfig{
+				Allow:     splitList(propertiesAllow),
+				Deny:      splitList(propertiesDeny),
+				Redact:    splitList(propertiesRedact),
+				MaxLength: propertiesMaxLength,
+			},
+		},
+		Scope: scopeConfig{
+			AppURL: scopeAppURL,
+			Token:  scopeToken,
+		},
+		ATC: atcConfig{
+			URL:             atcUrl,
+			Auth:            atcAuth,
+			Username:        atcUsername,
+			Password:        atcPassword,
+			PasswordFile:    atcPasswordFile,
+			Token:           atcToken,
+			FlyTarget:       atcFlyTarget,
+			Teams:           splitList(atcTeams),
+			RefreshInterval: atcRefreshInterval,
+			CACert:          atcCACert,
+			ClientCert:      atcClientCert,
+			ClientKey:       atcClientKey,
+			Insecure:        atcInsecure,
+		},
+		CF: cfConfig{
+			Enabled:      cfEnabled,
+			APIURL:       cfAPIURL,
+			UAAURL:       cfUAAURL,
+			ClientID:     cfClientID,
+			ClientSecret: cfClientSecret,
+			NameTTL:      cfNameTTL,
+		},
+		Metrics: metricsConfig{
+			Addr: metricsAddr,
+		},
+	}
+}
+
+// loadConfig returns the configuration from flags and environment variables,
+// overridden by the config file at path unless it is empty.
+func loadConfig(path string) (config, error) {
+	c := configFromFlags()
+
+	if path != "" {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return c, fmt.Errorf("error reading config file %q: %v", path, err)
+		}
+
+		if err := yaml.UnmarshalStrict(data, &c); err != nil {
+			return c, fmt.Errorf("error parsing config file %q: %v", path, err)
+		}
+	}
+
+	if c.Hostname == "" {
+		var err error
+		c.Hostname, err = os.Hostname()
+		if err != nil {
+			return c, err
+		}
+	}
+
+	if err := c.validate(); err != nil {
+		return c, fmt.Errorf("invalid configuration: %v", err)
+	}
+
+	return c, nil
+}
+
+func (c config) validate() error {
+	if c.PluginsRoot == "" {
+		return fmt.Errorf("plugins_root must not be empty")
+	}
+
+	if c.Garden.Network != "unix" && c.Garden.Network != "tcp" {
+		return fmt.Errorf("unknown garden network %q", c.Garden.Network)
+	}
+
+	if c.Garden.Addr == "" {
+		return fmt.Errorf("garden addr must not be empty")
+	}
+
+	for name, d := range map[string]time.Duration{
+		"garden refresh_interval":  c.Garden.RefreshInterval,
+		"garden refresh_timeout":   c.Garden.RefreshTimeout,
+		"garden container_timeout": c.Garden.ContainerTimeout,
+	} {
+		if d <= 0 {
+			return fmt.Errorf("%s must be positive", name)
+		}
+	}
+
+	if c.Garden.Workers < 1 {
+		return fmt.Errorf("garden workers must be at least 1")
+	}
+
+	if err := c.pluginConfig().Properties.Validate(); err != nil {
+		return fmt.Errorf("garden properties: %v", err)
+	}
+
+	if err := validateURL(c.Scope.AppURL); err != nil {
+		return fmt.Errorf("scope app_url: %v", err)
+	}
+
+	if c.atcEnabled() {
+		if c.ATC.URL != "" {
+			if err := validateURL(c.ATC.URL); err != nil {
+				return fmt.Errorf("atc url: %v", err)
+			}
+		}
+
+		if c.ATC.RefreshInterval <= 0 {
+			return fmt.Errorf("atc refresh_interval must be positive")
+		}
+
+		if err := c.credentials().Validate(); err != nil {
+			return fmt.Errorf("atc: %v", err)
+		}
+
+		if err := c.tlsConfig().Validate(); err != nil {
+			return fmt.Errorf("atc: %v", err)
+		}
+	}
+
+	if c.CF.Enabled && c.CF.APIURL != "" {
+		if err := validateURL(c.CF.APIURL); err != nil {
+			return fmt.Errorf("cf api_url: %v", err)
+		}
+
+		if c.CF.NameTTL <= 0 {
+			return fmt.Errorf("cf name_ttl must be positive")
+		}
+
+		if c.CF.UAAURL != "" {
+			if err := validateURL(c.CF.UAAURL); err != nil {
+				return fmt.Errorf("cf uaa_url: %v", err)
+			}
+		}
+
+		if (c.CF.ClientID == "") != (c.CF.ClientSecret == "") {
+			return fmt.Errorf("cf client_id and client_secret must be set together")
+		}
+	}
+
+	if c.Metrics.Addr != "" {
+		if _, _, err := net.SplitHostPort(c.Metrics.Addr); err != nil {
+			return fmt.Errorf("metrics addr: %v", err)
+		}
+	}
+
+	return nil
+}
+
+func validateURL(s string) error {
+	u, err := url.Parse(s)
+	if err != nil {
+		return err
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("%q is not an http(s) URL", s)
+	}
+
+	return nil
+}
+
+// atcEnabled returns whether containers are enriched with Concourse data.
+func (c config) atcEnabled() bool {
+	return c.ATC.URL != "" || c.ATC.Auth == conchhorse.CredentialsFlyrc
+}
+
+func (c config) credentials() conchhorse.Credentials {
+	return conchhorse.Credentials{
+		Source:       c.ATC.Auth,
+		Username:     c.ATC.Username,
+		Password:     c.ATC.Password,
+		PasswordFile: c.ATC.PasswordFile,
+		Token:        c.ATC.Token,
+		FlyTarget:    c.ATC.FlyTarget,
+	}
+}
+
+func (c config) tlsConfig() conchhorse.TLSConfig {
+	return conchhorse.TLSConfig{
+		CACertFile:     c.ATC.CACert,
+		ClientCertFile: c.ATC.ClientCert,
+		ClientKeyFile:  c.ATC.ClientKey,
+		Insecure:       c.ATC.Insecure,
+	}
+}
+
+func (c config) pluginConfig() garden.Config {
+	cpuCores := 1
+	if c.Garden.NormalizeCPU {
+		cpuCores = runtime.NumCPU()
+	}
+
+	return garden.Config{
+		Hostname:         c.Hostname,
+		GardenNetwork:    c.Garden.Network,
+		GardenAddr:       c.Garden.Addr,
+		ScopeAppURL:      c.Scope.AppURL,
+		ScopeToken:       c.Scope.Token,
+		RefreshInterval:  c.Garden.RefreshInterval,
+		RefreshTimeout:   c.Garden.RefreshTimeout,
+		ContainerTimeout: c.Garden.ContainerTimeout,
+		Workers:          c.Garden.Workers,
+		CPUCores:         cpuCores,
+		DepotDir:         c.Garden.DepotDir,
+		CgroupRoot:       c.Garden.CgroupRoot,
+		Properties: garden.PropertyRules{
+			Allow:          c.Garden.Properties.Allow,
+			Deny:           c.Garden.Properties.Deny,
+			Redact:         c.Garden.Properties.Redact,
+			MaxValueLength: c.Garden.Properties.MaxLength,
+		},
+	}
+}
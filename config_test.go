@@ -0,0 +1,87 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("config", func() {
+	var dir string
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "scope-garden-config")
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	write := func(content string) string {
+		path := filepath.Join(dir, "config.yml")
+		Expect(ioutil.WriteFile(path, []byte(content), 0600)).To(Succeed())
+		return path
+	}
+
+	It("uses flags and environment variables without a config file", func() {
+		c, err := loadConfig("")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(c.Garden.Network).To(Equal("unix"))
+		Expect(c.ATC.RefreshInterval).To(Equal(3 * time.Second))
+		Expect(c.Hostname).ToNot(BeEmpty())
+	})
+
+	It("overrides flags with the config file", func() {
+		c, err := loadConfig(write(`
+hostname: cell-1
+garden:
+  refresh_interval: 10s
+  properties:
+    deny: ["*secret*"]
+atc:
+  url: https://ci.example.com
+  username: admin
+  teams: [main, other]
+  refresh_interval: 1m
+cf:
+  enabled: true
+`))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(c.Hostname).To(Equal("cell-1"))
+		Expect(c.Garden.RefreshInterval).To(Equal(10 * time.Second))
+		Expect(c.Garden.RefreshTimeout).To(Equal(10 * time.Second))
+		Expect(c.Garden.Properties.Deny).To(Equal([]string{"*secret*"}))
+		Expect(c.ATC.Teams).To(Equal([]string{"main", "other"}))
+		Expect(c.ATC.RefreshInterval).To(Equal(time.Minute))
+		Expect(c.atcEnabled()).To(BeTrue())
+		Expect(c.CF.Enabled).To(BeTrue())
+	})
+
+	It("rejects unknown options", func() {
+		_, err := loadConfig(write("garden:\n  refresh: 10s\n"))
+		Expect(err).To(MatchError(ContainSubstring("error parsing config file")))
+	})
+
+	It("rejects invalid options", func() {
+		for _, content := range []string{
+			"garden:\n  network: udp\n",
+			"garden:\n  workers: 0\n",
+			"garden:\n  refresh_timeout: 0s\n",
+			"garden:\n  properties:\n    redact: ['(']\n",
+			"scope:\n  app_url: localhost:4040\n",
+			"atc:\n  url: https://ci.example.com\n  auth: kerberos\n",
+			"atc:\n  url: https://ci.example.com\n  username: admin\n  ca_cert: /does/not/exist\n",
+			"cf:\n  enabled: true\n  api_url: https://api.example.com\n  name_ttl: 0s\n",
+			"metrics:\n  addr: 9100\n",
+		} {
+			_, err := loadConfig(write(content))
+			Expect(err).To(MatchError(HavePrefix("invalid configuration")), content)
+		}
+	})
+})
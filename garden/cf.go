@@ -0,0 +1,123 @@
+package garden
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"code.cloudfoundry.org/garden"
+)
+
+const (
+	CFAppID         = "network.app_id"
+	CFSpaceID       = "network.space_id"
+	CFOrgID         = "network.org_id"
+	CFPolicyGroupID = "network.policy_group_id"
+	CFLogConfig     = "log_config"
+
+	ContainerCFPrefix = "cf_"
+)
+
+// CFNames resolves the names of Cloud Foundry apps, spaces and orgs. Lookups
+// must not block, and return false if a name is not known yet. Err returns
+// why names cannot be resolved currently, e.g. rejected credentials.
+type CFNames interface {
+	AppName(guid string) (string, bool)
+	SpaceName(guid string) (string, bool)
+	OrgName(guid string) (string, bool)
+	Err() error
+}
+
+type cfEnricher struct {
+	names CFNames
+}
+
+// cfLogConfig is the part of the log configuration Diego stores in the
+// properties of app containers that identifies the instance.
+type cfLogConfig struct {
+	Index *int `json:"index"`
+}
+
+// NewCFEnricher returns an enricher that names the containers of Diego cells
+// after the app and instance they run, and groups them by app. Names are
+// resolved with names if it is not nil, otherwise GUIDs are shown.
+func NewCFEnricher(names CFNames) Enricher {
+	return &cfEnricher{names: names}
+}
+
+func (e *cfEnricher) Enrich(handle string, info garden.ContainerInfo) (Enrichment, bool) {
+	appID := info.Properties[CFAppID]
+	if appID == "" {
+		return Enrichment{}, false
+	}
+
+	spaceID := info.Properties[CFSpaceID]
+	orgID := info.Properties[CFOrgID]
+
+	app := e.name(appID, e.appName)
+	space := e.name(spaceID, e.spaceName)
+	org := e.name(orgID, e.orgName)
+
+	rows := map[string]string{
+		"app id":          appID,
+		"space id":        spaceID,
+		"org id":          orgID,
+		"policy group id": info.Properties[CFPolicyGroupID],
+	}
+
+	if app != appID {
+		rows["app"] = app
+	}
+	if space != spaceID {
+		rows["space"] = space
+	}
+	if org != orgID {
+		rows["org"] = org
+	}
+
+	return Enrichment{
+		Name:  fmt.Sprintf("%s/%s", app, cfInstance(handle, info)),
+		Image: app,
+		Tables: []Table{{
+			ID:    ContainerCFPrefix,
+			Label: "Cloud Foundry",
+			Rows:  rows,
+		}},
+	}, true
+}
+
+func (e *cfEnricher) appName(guid string) (string, bool) {
+	return e.names.AppName(guid)
+}
+
+func (e *cfEnricher) spaceName(guid string) (string, bool) {
+	return e.names.SpaceName(guid)
+}
+
+func (e *cfEnricher) orgName(guid string) (string, bool) {
+	return e.names.OrgName(guid)
+}
+
+// name returns the resolved name of a GUID, or the GUID itself if the name
+// is not known.
+func (e *cfEnricher) name(guid string, lookup func(string) (string, bool)) string {
+	if e.names == nil || guid == "" {
+		return guid
+	}
+
+	if name, found := lookup(guid); found && name != "" {
+		return name
+	}
+
+	return guid
+}
+
+// cfInstance returns the index of an app instance, or the beginning of its
+// handle if the container does not say.
+func cfInstance(handle string, info garden.ContainerInfo) string {
+	var config cfLogConfig
+	if err := json.Unmarshal([]byte(info.Properties[CFLogConfig]), &config); err == nil && config.Index != nil {
+		return fmt.Sprintf("%d", *config.Index)
+	}
+
+	return shortHandle(handle)
+}
@@ -0,0 +1,57 @@
+package garden
+
+import (
+	"code.cloudfoundry.org/garden"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type fakeCFNames map[string]string
+
+func (n fakeCFNames) AppName(guid string) (string, bool) {
+	name, found := n[guid]
+	return name, found
+}
+
+func (n fakeCFNames) SpaceName(guid string) (string, bool) { return n.AppName(guid) }
+func (n fakeCFNames) OrgName(guid string) (string, bool)   { return n.AppName(guid) }
+func (n fakeCFNames) Err() error                           { return nil }
+
+var _ = Describe("cf enricher", func() {
+	info := garden.ContainerInfo{Properties: garden.Properties{
+		CFAppID:         "app-guid",
+		CFSpaceID:       "space-guid",
+		CFOrgID:         "org-guid",
+		CFPolicyGroupID: "policy-guid",
+		CFLogConfig:     `{"guid":"app-guid","index":2,"source_name":"CELL"}`,
+	}}
+
+	It("names containers after their app and instance", func() {
+		e, ok := NewCFEnricher(fakeCFNames{"app-guid": "my-app", "org-guid": "my-org"}).Enrich("0123456789", info)
+		Expect(ok).To(BeTrue())
+		Expect(e.Name).To(Equal("my-app/2"))
+		Expect(e.Image).To(Equal("my-app"))
+		Expect(e.Tables[0].Rows).To(Equal(map[string]string{
+			"app":             "my-app",
+			"app id":          "app-guid",
+			"space id":        "space-guid",
+			"org":             "my-org",
+			"org id":          "org-guid",
+			"policy group id": "policy-guid",
+		}))
+	})
+
+	It("falls back to GUIDs and handles", func() {
+		e, ok := NewCFEnricher(nil).Enrich("0123456789", garden.ContainerInfo{Properties: garden.Properties{
+			CFAppID: "app-guid",
+		}})
+		Expect(ok).To(BeTrue())
+		Expect(e.Name).To(Equal("app-guid/01234"))
+		Expect(e.Image).To(Equal("app-guid"))
+	})
+
+	It("ignores containers that are not app instances", func() {
+		_, ok := NewCFEnricher(nil).Enrich("handle", garden.ContainerInfo{})
+		Expect(ok).To(BeFalse())
+	})
+})
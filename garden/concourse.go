@@ -0,0 +1,309 @@
+package garden
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/garden"
+	"github.com/concourse/atc"
+)
+
+const (
+	PipelineTopology = "concourse_pipeline"
+	JobTopology      = "concourse_job"
+	BuildTopology    = "concourse_build"
+	WorkerTopology   = "concourse_worker"
+	VolumeTopology   = "concourse_volume"
+
+	TeamName     = "concourse_team_name"
+	PipelineName = "concourse_pipeline_name"
+	JobName      = "concourse_job_name"
+	BuildName    = "concourse_build_name"
+	BuildID      = "concourse_build_id"
+
+	WorkerName             = "concourse_worker_name"
+	WorkerState            = "concourse_worker_state"
+	WorkerPlatform         = "concourse_worker_platform"
+	WorkerTags             = "concourse_worker_tags"
+	WorkerTeam             = "concourse_worker_team"
+	WorkerVersion          = "concourse_worker_version"
+	WorkerStartTime        = "concourse_worker_start_time"
+	WorkerEphemeral        = "concourse_worker_ephemeral"
+	WorkerActiveContainers = "concourse_worker_active_containers"
+	WorkerActiveVolumes    = "concourse_worker_active_volumes"
+
+	VolumeHandle       = "concourse_volume_handle"
+	VolumeType         = "concourse_volume_type"
+	VolumeResourceType = "concourse_volume_resource_type"
+	VolumePipeline     = "concourse_volume_pipeline"
+	VolumeJob          = "concourse_volume_job"
+	VolumeStep         = "concourse_volume_step"
+	VolumeContainer    = "concourse_volume_container"
+	VolumeParent       = "concourse_volume_parent"
+	VolumePath         = "concourse_volume_path"
+	VolumeOrphaned     = "concourse_volume_orphaned"
+)
+
+// ConcourseDirectory looks up the Concourse containers, workers and volumes
+// known to an ATC.
+type ConcourseDirectory interface {
+	ConcourseContainer(handle string) (atc.Container, string, bool)
+	Workers() []atc.Worker
+	Volumes() []atc.Volume
+	Err() error
+}
+
+type concourseEnricher struct {
+	directory ConcourseDirectory
+}
+
+// NewConcourseEnricher returns an enricher that names containers after the
+// Concourse steps they run and links them to their pipelines, jobs, builds,
+// workers and volumes.
+func NewConcourseEnricher(directory ConcourseDirectory) Enricher {
+	return &concourseEnricher{directory: directory}
+}
+
+func (e *concourseEnricher) Enrich(handle string, info garden.ContainerInfo) (Enrichment, bool) {
+	c, team, found := e.directory.ConcourseContainer(handle)
+	if !found {
+		return Enrichment{}, false
+	}
+
+	return Enrichment{
+		Name:  fmt.Sprintf("%s/%s", c.StepName, shortHandle(handle)),
+		Image: c.StepName,
+		Tables: []Table{{
+			ID:    ContainerConcoursePrefix,
+			Label: "Concourse",
+			Rows: map[string]string{
+				"build number": c.BuildName,
+				"pipeline":     c.PipelineName,
+				"job":          c.JobName,
+				"type":         c.Type,
+				"team":         team,
+			},
+		}},
+		Labels: map[string]string{
+			"pipeline": c.PipelineName,
+			"job":      c.JobName,
+			"step":     c.StepName,
+			"team":     team,
+		},
+	}, true
+}
+
+func (e *concourseEnricher) addParents(r *report, host, handle string) map[string][]string {
+	c, team, found := e.directory.ConcourseContainer(handle)
+	if !found {
+		return nil
+	}
+
+	return r.addConcourseNodes(host, team, c)
+}
+
+func (e *concourseEnricher) addTopologies(r *report) {
+	r.AddWorkers(e.directory.Workers())
+	r.AddVolumes(e.directory.Volumes())
+}
+
+// addConcourseNodes adds pipeline, job and build nodes for a Concourse
+// container and returns the parents that link the Garden container to them.
+func (r *report) addConcourseNodes(host, team string, c atc.Container) map[string][]string {
+	parents := map[string][]string{}
+
+	if c.PipelineName == "" {
+		return parents
+	}
+
+	pipeline := nodeSpec{
+		ID:       fmt.Sprintf("%s/%s;<%s>", team, c.PipelineName, PipelineTopology),
+		Topology: PipelineTopology,
+		Parents:  map[string][]string{"host": {host}},
+		Latest: map[string]latestSpec{
+			"name":       latest(c.PipelineName),
+			TeamName:     latest(team),
+			PipelineName: latest(c.PipelineName),
+		},
+	}
+	r.ConcoursePipeline.Nodes[pipeline.ID] = pipeline
+	parents[PipelineTopology] = []string{pipeline.ID}
+
+	if c.JobName == "" {
+		return parents
+	}
+
+	job := nodeSpec{
+		ID:       fmt.Sprintf("%s/%s/%s;<%s>", team, c.PipelineName, c.JobName, JobTopology),
+		Topology: JobTopology,
+		Parents: map[string][]string{
+			"host":           {host},
+			PipelineTopology: {pipeline.ID},
+		},
+		Latest: map[string]latestSpec{
+			"name":       latest(fmt.Sprintf("%s/%s", c.PipelineName, c.JobName)),
+			TeamName:     latest(team),
+			PipelineName: latest(c.PipelineName),
+			JobName:      latest(c.JobName),
+		},
+	}
+	r.ConcourseJob.Nodes[job.ID] = job
+	parents[JobTopology] = []string{job.ID}
+
+	if c.BuildID == 0 {
+		return parents
+	}
+
+	build := nodeSpec{
+		ID:       fmt.Sprintf("%d;<%s>", c.BuildID, BuildTopology),
+		Topology: BuildTopology,
+		Parents: map[string][]string{
+			"host":           {host},
+			PipelineTopology: {pipeline.ID},
+			JobTopology:      {job.ID},
+		},
+		Latest: map[string]latestSpec{
+			"name":       latest(fmt.Sprintf("%s/%s #%s", c.PipelineName, c.JobName, c.BuildName)),
+			TeamName:     latest(team),
+			PipelineName: latest(c.PipelineName),
+			JobName:      latest(c.JobName),
+			BuildName:    latest(c.BuildName),
+			BuildID:      latest(fmt.Sprintf("%d", c.BuildID)),
+		},
+	}
+	r.ConcourseBuild.Nodes[build.ID] = build
+	parents[BuildTopology] = []string{build.ID}
+
+	return parents
+}
+
+func newConcourseTopology(label, labelPlural string, templates map[string]metadataTemplateSpec) topologySpec {
+	return topologySpec{
+		Label:             label,
+		LabelPlural:       labelPlural,
+		Shape:             "heptagon",
+		MetadataTemplates: templates,
+		Nodes:             map[string]nodeSpec{},
+	}
+}
+
+// AddWorkers adds a node for every Concourse worker and links the worker
+// that runs on this host to the local host node, which also gets the
+// worker's details so that Scope's host view shows them.
+func (r *report) AddWorkers(workers []atc.Worker) {
+	host := hostNodeID(r.hostname)
+
+	for _, w := range workers {
+		n := nodeSpec{
+			ID:       fmt.Sprintf("%s;<%s>", w.Name, WorkerTopology),
+			Topology: WorkerTopology,
+			Latest: map[string]latestSpec{
+				"name":                 latest(w.Name),
+				WorkerName:             latest(w.Name),
+				WorkerState:            latest(w.State),
+				WorkerPlatform:         latest(w.Platform),
+				WorkerTags:             latest(strings.Join(w.Tags, ", ")),
+				WorkerTeam:             latest(w.Team),
+				WorkerVersion:          latest(w.Version),
+				WorkerStartTime:        latest(time.Unix(w.StartTime, 0).UTC().Format(time.RFC3339)),
+				WorkerEphemeral:        latest(strconv.FormatBool(w.Ephemeral)),
+				WorkerActiveContainers: latest(strconv.Itoa(w.ActiveContainers)),
+				WorkerActiveVolumes:    latest(strconv.Itoa(w.ActiveVolumes)),
+			},
+		}
+
+		if w.Name == r.hostname {
+			n.Parents = map[string][]string{"host": {host}}
+			r.addWorkerToHost(host, n)
+		}
+
+		r.ConcourseWorker.Nodes[n.ID] = n
+	}
+}
+
+func (r *report) addWorkerToHost(host string, worker nodeSpec) {
+	n, found := r.Host.Nodes[host]
+	if !found {
+		n = nodeSpec{ID: host, Topology: "host", Latest: map[string]latestSpec{}}
+	}
+
+	for k, v := range worker.Latest {
+		if k != "name" && k != WorkerName {
+			n.Latest[k] = v
+		}
+	}
+
+	r.Host.Nodes[host] = n
+}
+
+// AddVolumes adds a node for every Concourse volume on this worker, linked to
+// the container that holds it and to the volume it was created from. The
+// volumes are also listed in a table of the container, so that Scope's
+// container view shows them. Volumes whose container is no longer reported
+// are marked as orphaned, which usually means they leaked.
+func (r *report) AddVolumes(volumes []atc.Volume) {
+	host := hostNodeID(r.hostname)
+
+	for _, v := range volumes {
+		if v.WorkerName != r.hostname {
+			continue
+		}
+
+		n := nodeSpec{
+			ID:       volumeNodeID(v.ID),
+			Topology: VolumeTopology,
+			Parents:  map[string][]string{"host": {host}},
+			Latest: map[string]latestSpec{
+				"name":             latest(v.ID),
+				VolumeHandle:       latest(v.ID),
+				VolumeType:         latest(v.Type),
+				VolumeResourceType: latest(volumeResourceType(v)),
+				VolumePipeline:     latest(v.PipelineName),
+				VolumeJob:          latest(v.JobName),
+				VolumeStep:         latest(v.StepName),
+				VolumeContainer:    latest(v.ContainerHandle),
+				VolumeParent:       latest(v.ParentHandle),
+				VolumePath:         latest(v.Path),
+			},
+		}
+
+		if v.ContainerHandle != "" {
+			containerID := fmt.Sprintf("%s;<container>", v.ContainerHandle)
+
+			if c, found := r.Container.Nodes[containerID]; found {
+				n.Parents["container"] = []string{containerID}
+				c.Latest[ContainerVolumesPrefix+v.ID] = latest(strings.TrimSpace(fmt.Sprintf("%s %s", v.Type, v.Path)))
+			} else {
+				n.Latest[VolumeOrphaned] = latest("true")
+			}
+		}
+
+		if v.ParentHandle != "" {
+			n.Parents[VolumeTopology] = []string{volumeNodeID(v.ParentHandle)}
+		}
+
+		r.ConcourseVolume.Nodes[n.ID] = n
+	}
+}
+
+func volumeNodeID(handle string) string {
+	return fmt.Sprintf("%s;<%s>", handle, VolumeTopology)
+}
+
+// volumeResourceType returns the base resource type a volume was ultimately
+// created from, following custom resource types down to their base.
+func volumeResourceType(v atc.Volume) string {
+	if v.BaseResourceType != nil {
+		return v.BaseResourceType.Name
+	}
+
+	for rt := v.ResourceType; rt != nil; rt = rt.ResourceType {
+		if rt.BaseResourceType != nil {
+			return rt.BaseResourceType.Name
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,254 @@
+package garden
+
+import (
+	"encoding/json"
+
+	"code.cloudfoundry.org/garden"
+	"github.com/concourse/atc"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type fakeDirectory struct {
+	containers map[string]atc.Container
+	workers    []atc.Worker
+	volumes    []atc.Volume
+	err        error
+}
+
+func (d *fakeDirectory) ConcourseContainer(handle string) (atc.Container, string, bool) {
+	c, found := d.containers[handle]
+	return c, "main", found
+}
+
+func (d *fakeDirectory) Workers() []atc.Worker { return d.workers }
+func (d *fakeDirectory) Volumes() []atc.Volume { return d.volumes }
+func (d *fakeDirectory) Err() error            { return d.err }
+
+var _ = Describe("concourse enricher", func() {
+	var (
+		dir *fakeDirectory
+		r   report
+	)
+
+	addContainer := func(handle string) nodeSpec {
+		Expect(r.AddNode(containerEntry{container: fakeContainer{handle: handle}})).To(Succeed())
+		return r.Container.Nodes[handle+";<container>"]
+	}
+
+	BeforeEach(func() {
+		dir = &fakeDirectory{containers: map[string]atc.Container{
+			"build-1": {StepName: "unit", PipelineName: "main", JobName: "test", BuildName: "7", BuildID: 42, Type: "task"},
+			"build-2": {StepName: "lint", PipelineName: "main", JobName: "test", BuildName: "7", BuildID: 42, Type: "task"},
+			"check":   {StepName: "repo", PipelineName: "main", Type: "check"},
+			"one-off": {StepName: "one-off", Type: "task"},
+		}}
+
+		r = newReport("worker", chain{NewConcourseEnricher(dir)}, propertyFilter{}, processSource{}, newCounters(1), newEventLog())
+	})
+
+	It("names containers after their step", func() {
+		e, ok := NewConcourseEnricher(dir).Enrich("build-1-handle", garden.ContainerInfo{})
+		Expect(ok).To(BeFalse())
+
+		e, ok = NewConcourseEnricher(dir).Enrich("build-1", garden.ContainerInfo{})
+		Expect(ok).To(BeTrue())
+		Expect(e.Name).To(Equal("unit/build"))
+		Expect(e.Image).To(Equal("unit"))
+		Expect(e.Tables[0].Rows).To(Equal(map[string]string{
+			"build number": "7",
+			"pipeline":     "main",
+			"job":          "test",
+			"type":         "task",
+			"team":         "main",
+		}))
+		Expect(e.Labels).To(Equal(map[string]string{
+			"pipeline": "main",
+			"job":      "test",
+			"step":     "unit",
+			"team":     "main",
+		}))
+	})
+
+	It("groups the containers of a build under their pipeline, job and build", func() {
+		first := addContainer("build-1")
+		second := addContainer("build-2")
+
+		Expect(r.ConcoursePipeline.Nodes).To(HaveLen(1))
+		Expect(r.ConcourseJob.Nodes).To(HaveLen(1))
+		Expect(r.ConcourseBuild.Nodes).To(HaveLen(1))
+
+		for _, n := range []nodeSpec{first, second} {
+			Expect(n.Parents[PipelineTopology]).To(Equal([]string{"main/main;<concourse_pipeline>"}))
+			Expect(n.Parents[JobTopology]).To(Equal([]string{"main/main/test;<concourse_job>"}))
+			Expect(n.Parents[BuildTopology]).To(Equal([]string{"42;<concourse_build>"}))
+		}
+
+		build := r.ConcourseBuild.Nodes["42;<concourse_build>"]
+		Expect(build.Latest["name"].Value).To(Equal("main/test #7"))
+		Expect(build.Parents[JobTopology]).To(Equal([]string{"main/main/test;<concourse_job>"}))
+		Expect(build.Parents["host"]).To(Equal([]string{"worker;<host>"}))
+	})
+
+	It("only links containers to what they belong to", func() {
+		check := addContainer("check")
+		Expect(check.Parents).To(HaveKey(PipelineTopology))
+		Expect(check.Parents).ToNot(HaveKey(JobTopology))
+		Expect(check.Parents).ToNot(HaveKey(BuildTopology))
+
+		oneOff := addContainer("one-off")
+		Expect(oneOff.Parents).ToNot(HaveKey(PipelineTopology))
+
+		other := addContainer("other")
+		Expect(other.Parents).To(Equal(map[string][]string{
+			"host":            {"worker;<host>"},
+			"container_image": {"not-found;<container_image>"},
+		}))
+	})
+
+	It("reports the custom topologies under their own keys", func() {
+		addContainer("build-1")
+
+		data, err := json.Marshal(r)
+		Expect(err).ToNot(HaveOccurred())
+
+		var topologies map[string]json.RawMessage
+		Expect(json.Unmarshal(data, &topologies)).To(Succeed())
+		Expect(topologies).To(HaveKey("ConcoursePipeline"))
+		Expect(topologies).To(HaveKey("ConcourseJob"))
+		Expect(topologies).To(HaveKey("ConcourseBuild"))
+	})
+
+	Describe("workers", func() {
+		BeforeEach(func() {
+			dir.workers = []atc.Worker{
+				{
+					Name:             "worker",
+					State:            "running",
+					Platform:         "linux",
+					Tags:             []string{"a", "b"},
+					Team:             "main",
+					Version:          "2.1",
+					StartTime:        1500000000,
+					Ephemeral:        true,
+					ActiveContainers: 12,
+					ActiveVolumes:    34,
+				},
+				{Name: "other", State: "stalled", ActiveContainers: 1},
+			}
+		})
+
+		It("reports every worker's state and counts", func() {
+			chain{NewConcourseEnricher(dir)}.addTopologies(&r)
+
+			Expect(r.ConcourseWorker.Nodes).To(HaveLen(2))
+
+			n := r.ConcourseWorker.Nodes["worker;<concourse_worker>"]
+			Expect(n.Latest[WorkerState].Value).To(Equal("running"))
+			Expect(n.Latest[WorkerPlatform].Value).To(Equal("linux"))
+			Expect(n.Latest[WorkerTags].Value).To(Equal("a, b"))
+			Expect(n.Latest[WorkerTeam].Value).To(Equal("main"))
+			Expect(n.Latest[WorkerVersion].Value).To(Equal("2.1"))
+			Expect(n.Latest[WorkerStartTime].Value).To(Equal("2017-07-14T02:40:00Z"))
+			Expect(n.Latest[WorkerEphemeral].Value).To(Equal("true"))
+			Expect(n.Latest[WorkerActiveContainers].Value).To(Equal("12"))
+			Expect(n.Latest[WorkerActiveVolumes].Value).To(Equal("34"))
+
+			other := r.ConcourseWorker.Nodes["other;<concourse_worker>"]
+			Expect(other.Latest[WorkerState].Value).To(Equal("stalled"))
+			Expect(other.Latest[WorkerActiveContainers].Value).To(Equal("1"))
+		})
+
+		It("links only the local worker to the host and shows it there", func() {
+			r.AddHost(&garden.Capacity{})
+			chain{NewConcourseEnricher(dir)}.addTopologies(&r)
+
+			Expect(r.ConcourseWorker.Nodes["worker;<concourse_worker>"].Parents["host"]).To(Equal([]string{"worker;<host>"}))
+			Expect(r.ConcourseWorker.Nodes["other;<concourse_worker>"].Parents).To(BeEmpty())
+
+			host := r.Host.Nodes["worker;<host>"]
+			Expect(host.Latest[HostMaxContainers].Value).To(Equal("0"))
+			Expect(host.Latest[WorkerState].Value).To(Equal("running"))
+			Expect(host.Latest[WorkerActiveVolumes].Value).To(Equal("34"))
+			Expect(r.Host.MetadataTemplates).To(HaveKey(WorkerState))
+		})
+
+		It("shows the local worker on the host when there is no host node", func() {
+			chain{NewConcourseEnricher(dir)}.addTopologies(&r)
+
+			host := r.Host.Nodes["worker;<host>"]
+			Expect(host.Topology).To(Equal("host"))
+			Expect(host.Latest[WorkerActiveContainers].Value).To(Equal("12"))
+		})
+	})
+
+	Describe("volumes", func() {
+		BeforeEach(func() {
+			dir.volumes = []atc.Volume{
+				{
+					ID:              "cache",
+					WorkerName:      "worker",
+					Type:            "resource",
+					ContainerHandle: "build-1",
+					Path:            "/tmp/build/get",
+					ParentHandle:    "base",
+					ResourceType: &atc.VolumeResourceType{
+						ResourceType: &atc.VolumeResourceType{
+							BaseResourceType: &atc.VolumeBaseResourceType{Name: "git"},
+						},
+					},
+					PipelineName: "main",
+					JobName:      "test",
+					StepName:     "repo",
+				},
+				{ID: "base", WorkerName: "worker", Type: "resource-type"},
+				{ID: "leaked", WorkerName: "worker", Type: "container", ContainerHandle: "destroyed"},
+				{ID: "remote", WorkerName: "other", ContainerHandle: "build-1"},
+			}
+		})
+
+		It("links volumes on this worker to their container and parent volume", func() {
+			addContainer("build-1")
+			chain{NewConcourseEnricher(dir)}.addTopologies(&r)
+
+			Expect(r.ConcourseVolume.Nodes).To(HaveLen(3))
+			Expect(r.ConcourseVolume.Nodes).ToNot(HaveKey("remote;<concourse_volume>"))
+
+			n := r.ConcourseVolume.Nodes["cache;<concourse_volume>"]
+			Expect(n.Parents["container"]).To(Equal([]string{"build-1;<container>"}))
+			Expect(n.Parents[VolumeTopology]).To(Equal([]string{"base;<concourse_volume>"}))
+			Expect(n.Parents["host"]).To(Equal([]string{"worker;<host>"}))
+			Expect(n.Latest[VolumeType].Value).To(Equal("resource"))
+			Expect(n.Latest[VolumeResourceType].Value).To(Equal("git"))
+			Expect(n.Latest[VolumePipeline].Value).To(Equal("main"))
+			Expect(n.Latest[VolumeJob].Value).To(Equal("test"))
+			Expect(n.Latest[VolumeStep].Value).To(Equal("repo"))
+			Expect(n.Latest).ToNot(HaveKey(VolumeOrphaned))
+
+			base := r.ConcourseVolume.Nodes["base;<concourse_volume>"]
+			Expect(base.Parents).ToNot(HaveKey("container"))
+			Expect(base.Latest).ToNot(HaveKey(VolumeOrphaned))
+		})
+
+		It("lists the volumes of a container in its details", func() {
+			addContainer("build-1")
+			chain{NewConcourseEnricher(dir)}.addTopologies(&r)
+
+			c := r.Container.Nodes["build-1;<container>"]
+			Expect(c.Latest[ContainerVolumesPrefix+"cache"].Value).To(Equal("resource /tmp/build/get"))
+			Expect(c.Latest).ToNot(HaveKey(ContainerVolumesPrefix + "remote"))
+			Expect(r.Container.TableTemplates).To(HaveKey(ContainerVolumesPrefix))
+		})
+
+		It("marks volumes whose container is gone as orphaned", func() {
+			chain{NewConcourseEnricher(dir)}.addTopologies(&r)
+
+			n := r.ConcourseVolume.Nodes["leaked;<concourse_volume>"]
+			Expect(n.Parents).ToNot(HaveKey("container"))
+			Expect(n.Latest[VolumeContainer].Value).To(Equal("destroyed"))
+			Expect(n.Latest[VolumeOrphaned].Value).To(Equal("true"))
+
+			Expect(r.ConcourseVolume.Nodes["cache;<concourse_volume>"].Latest[VolumeOrphaned].Value).To(Equal("true"))
+		})
+	})
+})
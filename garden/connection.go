@@ -0,0 +1,51 @@
+package garden
+
+import (
+	"net"
+	"time"
+
+	gardenclient "code.cloudfoundry.org/garden/client"
+	gardenconnection "code.cloudfoundry.org/garden/client/connection"
+	"code.cloudfoundry.org/lager"
+)
+
+// dialTimeout is the time allowed for connecting to the Garden server, as in
+// the default Garden connection.
+const dialTimeout = 2 * time.Second
+
+// newRefreshClient returns a Garden client whose requests fail once they
+// take longer than timeout. The client disables keep-alives, so every
+// request dials its own connection, and the deadline of that connection
+// bounds the whole request. Calls made by a refresh therefore always end,
+// even if the Garden server hangs.
+//
+// The client must not be used to run processes, whose streams outlive the
+// deadline.
+func newRefreshClient(network, addr string, timeout time.Duration) gardenclient.Client {
+	return gardenclient.New(gardenconnection.NewWithDialerAndLogger(
+		deadlineDialer(network, addr, timeout),
+		lager.NewLogger("garden-connection"),
+	))
+}
+
+// newControlClient returns a Garden client without request deadlines, used
+// for controls and pipes.
+func newControlClient(network, addr string) gardenclient.Client {
+	return gardenclient.New(gardenconnection.New(network, addr))
+}
+
+func deadlineDialer(network, addr string, timeout time.Duration) gardenconnection.DialerFunc {
+	return func(string, string) (net.Conn, error) {
+		conn, err := net.DialTimeout(network, addr, dialTimeout)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+			conn.Close()
+			return nil, err
+		}
+
+		return conn, nil
+	}
+}
@@ -0,0 +1,46 @@
+package garden
+
+import (
+	"net"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("refresh client", func() {
+	var listener net.Listener
+
+	BeforeEach(func() {
+		var err error
+		listener, err = net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).ToNot(HaveOccurred())
+
+		// Accept connections but never respond, like a hung Garden server.
+		go func() {
+			for {
+				conn, err := listener.Accept()
+				if err != nil {
+					return
+				}
+				defer conn.Close()
+			}
+		}()
+	})
+
+	AfterEach(func() {
+		listener.Close()
+	})
+
+	It("fails requests that exceed the timeout", func() {
+		client := newRefreshClient("tcp", listener.Addr().String(), 100*time.Millisecond)
+
+		done := make(chan error, 1)
+		go func() {
+			_, err := client.BulkMetrics([]string{"a"})
+			done <- err
+		}()
+
+		Eventually(done, time.Second).Should(Receive(HaveOccurred()))
+	})
+})
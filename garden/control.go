@@ -0,0 +1,131 @@
+package garden
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	StopContainer = "garden_stop_container"
+	KillContainer = "garden_kill_container"
+)
+
+type controlRequest struct {
+	AppID       string
+	NodeID      string
+	Control     string
+	ControlArgs map[string]string
+}
+
+type controlResponse struct {
+	Value            interface{} `json:"value,omitempty"`
+	Error            string      `json:"error,omitempty"`
+	Pipe             string      `json:"pipe,omitempty"`
+	RawTTY           bool        `json:"raw_tty,omitempty"`
+	ResizeTTYControl string      `json:"resize_tty_control,omitempty"`
+}
+
+func (p *plugin) Control(w http.ResponseWriter, r *http.Request) {
+	var req controlRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Printf("error decoding control request: %v\n", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	res := p.control(req)
+
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		log.Printf("error encoding control response: %v\n", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (p *plugin) control(req controlRequest) controlResponse {
+	handle, err := containerHandle(req.NodeID)
+	if err != nil {
+		return controlResponse{Error: err.Error()}
+	}
+
+	p.lock.RLock()
+	registry := p.registry
+	p.lock.RUnlock()
+
+	switch req.Control {
+	case StopContainer:
+		err = registry.stopContainer(handle, false)
+	case KillContainer:
+		err = registry.stopContainer(handle, true)
+	case ExecShell:
+		return p.execShell(registry, handle)
+	case ResizeExecTTY:
+		err = p.resizeExecTTY(req.ControlArgs)
+	default:
+		err = fmt.Errorf("unknown control %q", req.Control)
+	}
+
+	if err != nil {
+		log.Printf("error handling control %q for container %q: %v\n", req.Control, handle, err)
+		return controlResponse{Error: err.Error()}
+	}
+
+	return controlResponse{}
+}
+
+func (p *plugin) execShell(registry *registry, handle string) controlResponse {
+	c, err := registry.lookup(handle)
+	if err != nil {
+		return controlResponse{Error: err.Error()}
+	}
+
+	id, err := p.pipes.exec(c)
+	if err != nil {
+		log.Printf("error executing shell in container %q: %v\n", handle, err)
+		return controlResponse{Error: err.Error()}
+	}
+
+	return controlResponse{
+		Pipe:             id,
+		RawTTY:           true,
+		ResizeTTYControl: ResizeExecTTY,
+	}
+}
+
+func (p *plugin) resizeExecTTY(args map[string]string) error {
+	width, height, err := ttySize(args)
+	if err != nil {
+		return err
+	}
+
+	return p.pipes.resize(args["pipeID"], width, height)
+}
+
+func containerHandle(nodeID string) (string, error) {
+	suffix := ";<container>"
+	if !strings.HasSuffix(nodeID, suffix) {
+		return "", fmt.Errorf("invalid container node id %q", nodeID)
+	}
+
+	return strings.TrimSuffix(nodeID, suffix), nil
+}
+
+func containerControls(state string) map[string]controlEntrySpec {
+	stopped := state == "stopped"
+
+	return map[string]controlEntrySpec{
+		StopContainer: controlEntry(stopped),
+		KillContainer: controlEntry(stopped),
+		ExecShell:     controlEntry(stopped),
+	}
+}
+
+func controlEntry(dead bool) controlEntrySpec {
+	return controlEntrySpec{
+		Timestamp: time.Now(),
+		Value:     controlDataSpec{Dead: dead},
+	}
+}
@@ -0,0 +1,123 @@
+package garden
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/garden"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// stoppableContainer records the kill flag of every call to Stop.
+type stoppableContainer struct {
+	fakeContainer
+	stops []bool
+}
+
+func (c *stoppableContainer) Stop(kill bool) error {
+	c.stops = append(c.stops, kill)
+	return nil
+}
+
+type lookupClient struct {
+	garden.Client
+	containers map[string]garden.Container
+}
+
+func (c *lookupClient) Lookup(handle string) (garden.Container, error) {
+	if container, found := c.containers[handle]; found {
+		return container, nil
+	}
+	return nil, garden.ContainerNotFoundError{Handle: handle}
+}
+
+var _ = Describe("controls", func() {
+	var (
+		container *stoppableContainer
+		p         *plugin
+	)
+
+	BeforeEach(func() {
+		container = &stoppableContainer{fakeContainer: fakeContainer{handle: "handle"}}
+		client := &lookupClient{containers: map[string]garden.Container{"handle": container}}
+
+		p = &plugin{
+			registry: newRegistry(client, client, 1, time.Second, time.Second),
+			pipes:    newPipes("http://scope", ""),
+		}
+	})
+
+	It("advertises the controls of running containers on their nodes", func() {
+		r := newReport("host", nil, propertyFilter{}, processSource{}, newCounters(1), newEventLog())
+		Expect(r.AddNode(containerEntry{
+			container: fakeContainer{handle: "running"},
+			info:      garden.ContainerInfo{State: "active"},
+		})).To(Succeed())
+		Expect(r.AddNode(containerEntry{
+			container: fakeContainer{handle: "stopped"},
+			info:      garden.ContainerInfo{State: "stopped"},
+		})).To(Succeed())
+
+		Expect(r.Container.Controls).To(HaveKey(StopContainer))
+		Expect(r.Container.Controls).To(HaveKey(KillContainer))
+		Expect(r.Container.Controls).To(HaveKey(ExecShell))
+
+		running := r.Container.Nodes["running;<container>"].LatestControls
+		Expect(running).To(HaveLen(3))
+		for _, c := range []string{StopContainer, KillContainer, ExecShell} {
+			Expect(running[c].Value.Dead).To(BeFalse())
+			Expect(r.Container.Nodes["stopped;<container>"].LatestControls[c].Value.Dead).To(BeTrue())
+		}
+	})
+
+	It("takes the container handle from the node ID", func() {
+		handle, err := containerHandle("a;b;<container>")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(handle).To(Equal("a;b"))
+
+		_, err = containerHandle("host;<host>")
+		Expect(err).To(MatchError(`invalid container node id "host;<host>"`))
+	})
+
+	It("stops and kills containers", func() {
+		Expect(p.control(controlRequest{NodeID: "handle;<container>", Control: StopContainer})).To(Equal(controlResponse{}))
+		Expect(p.control(controlRequest{NodeID: "handle;<container>", Control: KillContainer})).To(Equal(controlResponse{}))
+
+		Expect(container.stops).To(Equal([]bool{false, true}))
+	})
+
+	It("rejects unknown controls", func() {
+		res := p.control(controlRequest{NodeID: "handle;<container>", Control: "garden_pause_container"})
+
+		Expect(res.Error).To(Equal(`unknown control "garden_pause_container"`))
+		Expect(container.stops).To(BeEmpty())
+	})
+
+	It("fails for containers that do not exist", func() {
+		for _, control := range []string{StopContainer, ExecShell} {
+			res := p.control(controlRequest{NodeID: "missing;<container>", Control: control})
+			Expect(res.Error).To(ContainSubstring(`error looking up container "missing"`))
+		}
+	})
+
+	It("serves control requests", func() {
+		body := `{"AppID":"app","NodeID":"handle;<container>","Control":"garden_kill_container"}`
+
+		w := httptest.NewRecorder()
+		p.Control(w, httptest.NewRequest("POST", "/control", strings.NewReader(body)))
+
+		var res controlResponse
+		Expect(json.NewDecoder(w.Body).Decode(&res)).To(Succeed())
+		Expect(res.Error).To(BeEmpty())
+		Expect(container.stops).To(Equal([]bool{true}))
+
+		w = httptest.NewRecorder()
+		p.Control(w, httptest.NewRequest("POST", "/control", strings.NewReader(`{"NodeID":"handle"}`)))
+
+		Expect(json.NewDecoder(w.Body).Decode(&res)).To(Succeed())
+		Expect(res.Error).To(Equal(`invalid container node id "handle"`))
+	})
+})
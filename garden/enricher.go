@@ -0,0 +1,181 @@
+package garden
+
+import (
+	"fmt"
+
+	"code.cloudfoundry.org/garden"
+)
+
+// Enricher adds metadata from sources other than Garden to containers.
+type Enricher interface {
+	// Enrich returns the metadata of the container with the given handle and
+	// false if the enricher knows nothing about the container. The property
+	// rules are applied to the returned enrichment.
+	Enrich(handle string, info garden.ContainerInfo) (Enrichment, bool)
+}
+
+// Enrichment is the metadata an Enricher adds to a container.
+type Enrichment struct {
+	// Name replaces the container's handle as its display name.
+	Name string
+	// Image groups the container with others of the same image.
+	Image string
+	// Latest holds fields shown in the container's details.
+	Latest []Field
+	// Tables hold fields shown as tables in the container's details.
+	Tables []Table
+	// Labels are added to the container's Prometheus metrics.
+	Labels map[string]string
+}
+
+// Field is a single labelled value. Its ID must be unique across enrichers.
+type Field struct {
+	ID    string
+	Label string
+	Value string
+}
+
+// Table is a labelled set of rows. Its ID is used as the prefix of the rows'
+// keys and must be unique across enrichers.
+type Table struct {
+	ID    string
+	Label string
+	Rows  map[string]string
+}
+
+// topologyEnricher is implemented by enrichers that add nodes of their own
+// topologies to the report.
+type topologyEnricher interface {
+	// addParents adds the nodes an enriched container belongs to and returns
+	// their IDs by topology.
+	addParents(r *report, host, handle string) map[string][]string
+	// addTopologies adds the nodes that are not tied to a container.
+	addTopologies(r *report)
+}
+
+type chain []Enricher
+
+// Chain returns an enricher that merges the enrichments of all given
+// enrichers. The name and image of earlier enrichers take precedence, as do
+// their fields, rows and labels if several enrichers use the same ID.
+func Chain(enrichers ...Enricher) Enricher {
+	return chain(enrichers)
+}
+
+func (c chain) Enrich(handle string, info garden.ContainerInfo) (Enrichment, bool) {
+	var (
+		merged   Enrichment
+		enriched bool
+		fields   = map[string]bool{}
+		tables   = map[string]int{}
+	)
+
+	for _, e := range c {
+		enrichment, ok := e.Enrich(handle, info)
+		if !ok {
+			continue
+		}
+
+		enriched = true
+
+		if merged.Name == "" {
+			merged.Name = enrichment.Name
+		}
+
+		if merged.Image == "" {
+			merged.Image = enrichment.Image
+		}
+
+		for k, v := range enrichment.Labels {
+			if merged.Labels == nil {
+				merged.Labels = map[string]string{}
+			}
+			if _, found := merged.Labels[k]; !found {
+				merged.Labels[k] = v
+			}
+		}
+
+		for _, f := range enrichment.Latest {
+			if !fields[f.ID] {
+				fields[f.ID] = true
+				merged.Latest = append(merged.Latest, f)
+			}
+		}
+
+		for _, t := range enrichment.Tables {
+			i, found := tables[t.ID]
+			if !found {
+				tables[t.ID] = len(merged.Tables)
+				merged.Tables = append(merged.Tables, Table{ID: t.ID, Label: t.Label, Rows: map[string]string{}})
+				i = len(merged.Tables) - 1
+			}
+
+			for k, v := range t.Rows {
+				if _, found := merged.Tables[i].Rows[k]; !found {
+					merged.Tables[i].Rows[k] = v
+				}
+			}
+		}
+	}
+
+	return merged, enriched
+}
+
+func (c chain) addParents(r *report, host, handle string) map[string][]string {
+	parents := map[string][]string{}
+
+	for _, e := range c {
+		if t, ok := e.(topologyEnricher); ok {
+			for topology, ids := range t.addParents(r, host, handle) {
+				parents[topology] = append(parents[topology], ids...)
+			}
+		}
+	}
+
+	return parents
+}
+
+func (c chain) addTopologies(r *report) {
+	for _, e := range c {
+		if t, ok := e.(topologyEnricher); ok {
+			t.addTopologies(r)
+		}
+	}
+}
+
+// addEnrichment adds the fields and tables of an enrichment to a container
+// node and declares their templates in the report.
+func (r *report) addEnrichment(n *nodeSpec, e Enrichment) {
+	for _, f := range e.Latest {
+		n.Latest[f.ID] = latest(f.Value)
+
+		if _, found := r.Container.MetadataTemplates[f.ID]; !found {
+			r.Container.MetadataTemplates[f.ID] = metadataTemplateSpec{
+				ID:       f.ID,
+				Label:    f.Label,
+				From:     "latest",
+				Priority: len(r.Container.MetadataTemplates) + 1,
+			}
+		}
+	}
+
+	for _, t := range e.Tables {
+		for k, v := range t.Rows {
+			n.Latest[fmt.Sprintf("%s%s", t.ID, k)] = latest(v)
+		}
+
+		if _, found := r.Container.TableTemplates[t.ID]; !found {
+			r.Container.TableTemplates[t.ID] = tableTemplateSpec{ID: t.ID, Label: t.Label, Prefix: t.ID}
+		}
+	}
+}
+
+// shortHandle returns the beginning of a container handle, which is enough to
+// tell containers with the same name apart.
+func shortHandle(handle string) string {
+	if len(handle) > 5 {
+		return handle[:5]
+	}
+
+	return handle
+}
@@ -0,0 +1,76 @@
+package garden
+
+import (
+	"code.cloudfoundry.org/garden"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type fakeEnricher map[string]Enrichment
+
+func (e fakeEnricher) Enrich(handle string, info garden.ContainerInfo) (Enrichment, bool) {
+	enrichment, found := e[handle]
+	return enrichment, found
+}
+
+var _ = Describe("enrichers", func() {
+	first := fakeEnricher{
+		"a": {
+			Name:   "first",
+			Latest: []Field{{ID: "owner", Label: "Owner", Value: "first"}},
+			Tables: []Table{{ID: "labels_", Label: "Labels", Rows: map[string]string{"team": "first"}}},
+		},
+	}
+
+	second := fakeEnricher{
+		"a": {
+			Name:   "second",
+			Image:  "app",
+			Latest: []Field{{ID: "owner", Label: "Owner", Value: "second"}},
+			Tables: []Table{{ID: "labels_", Label: "Labels", Rows: map[string]string{"team": "second", "env": "prod"}}},
+		},
+		"b": {Name: "b"},
+	}
+
+	It("prefers earlier enrichers in a chain", func() {
+		e, ok := Chain(first, second).Enrich("a", garden.ContainerInfo{})
+		Expect(ok).To(BeTrue())
+		Expect(e.Name).To(Equal("first"))
+		Expect(e.Image).To(Equal("app"))
+		Expect(e.Latest).To(Equal([]Field{{ID: "owner", Label: "Owner", Value: "first"}}))
+		Expect(e.Tables).To(Equal([]Table{{
+			ID:    "labels_",
+			Label: "Labels",
+			Rows:  map[string]string{"team": "first", "env": "prod"},
+		}}))
+	})
+
+	It("only enriches containers known to an enricher", func() {
+		_, ok := Chain(first).Enrich("b", garden.ContainerInfo{})
+		Expect(ok).To(BeFalse())
+
+		e, ok := Chain(first, second).Enrich("b", garden.ContainerInfo{})
+		Expect(ok).To(BeTrue())
+		Expect(e.Name).To(Equal("b"))
+	})
+
+	It("adds enrichments to container nodes", func() {
+		r := newReport("host", chain{first, second}, propertyFilter{}, processSource{}, newCounters(1), newEventLog())
+
+		Expect(r.AddNode(containerEntry{container: fakeContainer{handle: "a"}})).To(Succeed())
+		Expect(r.AddNode(containerEntry{container: fakeContainer{handle: "c"}})).To(Succeed())
+
+		a := r.Container.Nodes["a;<container>"]
+		Expect(a.Latest["docker_container_name"].Value).To(Equal("first"))
+		Expect(a.Latest["docker_image_id"].Value).To(Equal("app"))
+		Expect(a.Latest["owner"].Value).To(Equal("first"))
+		Expect(a.Latest["labels_env"].Value).To(Equal("prod"))
+		Expect(r.Container.MetadataTemplates).To(HaveKey("owner"))
+		Expect(r.Container.TableTemplates).To(HaveKey("labels_"))
+		Expect(r.ContainerImage.Nodes).To(HaveKey("app;<container_image>"))
+
+		c := r.Container.Nodes["c;<container>"]
+		Expect(c.Latest["docker_container_name"].Value).To(Equal("c"))
+		Expect(containerMetadataTemplates).ToNot(HaveKey("owner"))
+	})
+})
@@ -0,0 +1,168 @@
+package garden
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+const oomEvent = "oom"
+
+// destroyedContainerTTL is how long a container that had events stays in the
+// report after Garden stopped listing it, so that an OOM kill remains visible
+// even if the container is destroyed right away.
+const destroyedContainerTTL = 15 * time.Minute
+
+// eventLog remembers when each container event was first observed. Garden
+// only reports the names of events, so the first refresh that sees an event
+// provides its timestamp.
+type eventLog struct {
+	lock       sync.Mutex
+	containers map[string]*containerEvents
+	observed   map[string]bool
+}
+
+type containerEvents struct {
+	events     map[string]time.Time
+	node       nodeSpec
+	observedAt time.Time
+}
+
+func newEventLog() *eventLog {
+	return &eventLog{
+		containers: map[string]*containerEvents{},
+		observed:   map[string]bool{},
+	}
+}
+
+// observe records the events of a container and returns the time each of
+// them was first seen.
+func (l *eventLog) observe(handle string, events []string, at time.Time) map[string]time.Time {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	var last map[string]time.Time
+	if c, found := l.containers[handle]; found {
+		last = c.events
+	}
+
+	seen := map[string]time.Time{}
+	for _, e := range events {
+		if t, found := last[e]; found {
+			seen[e] = t
+		} else if _, found := seen[e]; !found {
+			seen[e] = at
+		}
+	}
+
+	l.containers[handle] = &containerEvents{events: seen, observedAt: at}
+	l.observed[handle] = true
+
+	return seen
+}
+
+// remember keeps the node of a container with events, so that it can still
+// be reported once the container is destroyed.
+func (l *eventLog) remember(handle string, n nodeSpec) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if c, found := l.containers[handle]; found && len(c.events) > 0 {
+		c.node = n
+	}
+}
+
+// rotate keeps the events observed during the current refresh for the next
+// one. Containers that were not observed are forgotten, unless they had
+// events and were last observed less than destroyedContainerTTL ago.
+func (l *eventLog) rotate(at time.Time) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	for handle, c := range l.containers {
+		if l.observed[handle] {
+			continue
+		}
+
+		if len(c.events) == 0 || at.Sub(c.observedAt) > destroyedContainerTTL {
+			delete(l.containers, handle)
+		}
+	}
+
+	l.observed = map[string]bool{}
+}
+
+// destroyed returns the last nodes of the containers with events that were
+// not observed during the current refresh, but less than
+// destroyedContainerTTL before at. It must be called before rotate.
+func (l *eventLog) destroyed(at time.Time) []nodeSpec {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	var nodes []nodeSpec
+	for handle, c := range l.containers {
+		if !l.observed[handle] && c.node.ID != "" && at.Sub(c.observedAt) <= destroyedContainerTTL {
+			nodes = append(nodes, c.node)
+		}
+	}
+
+	return nodes
+}
+
+// addDestroyed adds the last node of a destroyed container that had events.
+// It keeps its details and events but no metrics, controls or links to
+// nodes that may no longer exist.
+func (r *report) addDestroyed(n nodeSpec) {
+	if _, found := r.Container.Nodes[n.ID]; found {
+		return
+	}
+
+	values := make(map[string]latestSpec, len(n.Latest)+1)
+	for k, v := range n.Latest {
+		values[k] = v
+	}
+
+	state := "destroyed"
+	if _, oom := values[ContainerOOMKilled]; oom {
+		state = "destroyed (oom killed)"
+	}
+	values[ContainerState] = latest(state)
+	delete(values, ContainerStale)
+
+	r.Container.Nodes[n.ID] = nodeSpec{
+		ID:       n.ID,
+		Topology: n.Topology,
+		Latest:   values,
+		Parents:  map[string][]string{"host": n.Parents["host"]},
+	}
+}
+
+func (r *report) addEvents(n *nodeSpec, events map[string]time.Time) {
+	var (
+		lastEvent string
+		lastTime  time.Time
+	)
+
+	for e, t := range events {
+		n.Latest[fmt.Sprintf("%s%s", ContainerEventsPrefix, e)] = latestSpec{
+			Timestamp: t,
+			Value:     t.UTC().Format(time.RFC3339),
+		}
+
+		if t.After(lastTime) || (t.Equal(lastTime) && e < lastEvent) {
+			lastEvent, lastTime = e, t
+		}
+	}
+
+	if lastEvent != "" {
+		n.Latest[ContainerLastEvent] = latestSpec{
+			Timestamp: lastTime,
+			Value:     fmt.Sprintf("%s at %s", lastEvent, lastTime.UTC().Format(time.RFC3339)),
+		}
+	}
+
+	if _, oom := events[oomEvent]; oom {
+		n.Latest[ContainerOOMKilled] = latest("true")
+		n.Latest[ContainerState] = latest(fmt.Sprintf("%s (oom killed)", n.Latest[ContainerState].Value))
+	}
+}
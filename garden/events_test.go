@@ -0,0 +1,99 @@
+package garden
+
+import (
+	"time"
+
+	"code.cloudfoundry.org/garden"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("eventLog", func() {
+	var (
+		l   *eventLog
+		now time.Time
+	)
+
+	BeforeEach(func() {
+		l = newEventLog()
+		now = time.Now()
+	})
+
+	It("keeps the time an event was first observed", func() {
+		l.observe("handle", []string{"oom"}, now)
+		l.rotate(now)
+
+		events := l.observe("handle", []string{"oom"}, now.Add(time.Minute))
+		Expect(events).To(Equal(map[string]time.Time{"oom": now}))
+	})
+
+	It("forgets containers without events that were not observed", func() {
+		l.observe("handle", nil, now)
+		l.rotate(now)
+		l.rotate(now)
+
+		Expect(l.containers).To(BeEmpty())
+	})
+
+	It("forgets destroyed containers with events after a while", func() {
+		l.observe("handle", []string{"oom"}, now)
+		l.rotate(now)
+		l.rotate(now.Add(destroyedContainerTTL))
+		Expect(l.containers).To(HaveKey("handle"))
+
+		l.rotate(now.Add(destroyedContainerTTL + time.Second))
+
+		events := l.observe("handle", []string{"oom"}, now.Add(time.Hour))
+		Expect(events["oom"]).To(Equal(now.Add(time.Hour)))
+	})
+
+	It("keeps reporting OOM killed containers after they are destroyed", func() {
+		r := newReport("host", nil, propertyFilter{}, processSource{}, newCounters(1), l)
+		Expect(r.AddNode(containerEntry{
+			container: fakeContainer{handle: "handle"},
+			info:      garden.ContainerInfo{State: "active", Events: []string{"oom"}},
+		})).To(Succeed())
+		l.rotate(now)
+
+		for _, at := range []time.Time{now.Add(time.Minute), now.Add(destroyedContainerTTL)} {
+			destroyed := l.destroyed(at)
+			l.rotate(at)
+
+			r = newReport("host", nil, propertyFilter{}, processSource{}, newCounters(1), l)
+			for _, n := range destroyed {
+				r.addDestroyed(n)
+			}
+
+			Expect(r.Container.Nodes).To(HaveKey("handle;<container>"))
+			n := r.Container.Nodes["handle;<container>"]
+			Expect(n.Latest[ContainerState].Value).To(Equal("destroyed (oom killed)"))
+			Expect(n.Latest[ContainerOOMKilled].Value).To(Equal("true"))
+			Expect(n.Latest["docker_container_name"].Value).To(Equal("handle (OOM)"))
+			Expect(n.Latest).To(HaveKey(ContainerEventsPrefix + "oom"))
+			Expect(n.Metrics).To(BeEmpty())
+			Expect(n.LatestControls).To(BeEmpty())
+		}
+
+		Expect(l.destroyed(now.Add(destroyedContainerTTL + time.Second))).To(BeEmpty())
+	})
+
+	It("does not keep destroyed containers without events", func() {
+		r := newReport("host", nil, propertyFilter{}, processSource{}, newCounters(1), l)
+		Expect(r.AddNode(containerEntry{container: fakeContainer{handle: "handle"}})).To(Succeed())
+		l.rotate(now)
+
+		Expect(l.destroyed(now.Add(time.Minute))).To(BeEmpty())
+	})
+
+	It("marks OOM killed containers", func() {
+		r := report{}
+		n := nodeSpec{Latest: map[string]latestSpec{ContainerState: latest("stopped")}}
+
+		r.addEvents(&n, map[string]time.Time{"oom": now})
+
+		Expect(n.Latest[ContainerOOMKilled].Value).To(Equal("true"))
+		Expect(n.Latest[ContainerState].Value).To(Equal("stopped (oom killed)"))
+		Expect(n.Latest[ContainerLastEvent].Timestamp).To(Equal(now))
+		Expect(n.Latest).To(HaveKey(ContainerEventsPrefix + "oom"))
+	})
+})
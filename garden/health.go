@@ -0,0 +1,135 @@
+package garden
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// staleRefreshes is the number of refresh intervals after which a report
+// without a successful refresh is considered stale.
+const staleRefreshes = 3
+
+type statusFn func() error
+
+// health tracks the outcome of report refreshes.
+type health struct {
+	lastRefresh   time.Time
+	gardenErr     error
+	containerErrs []error
+}
+
+type healthStatus struct {
+	Status      string    `json:"status"`
+	Healthy     bool      `json:"healthy"`
+	LastRefresh time.Time `json:"last_refresh"`
+	Garden      string    `json:"garden"`
+	ATC         string    `json:"atc"`
+	CF          string    `json:"cf"`
+	Containers  string    `json:"containers"`
+	// ContainerErrors lists why containers could not be reported. They do
+	// not affect Healthy or Status.
+	ContainerErrors []string `json:"container_errors,omitempty"`
+}
+
+func (p *plugin) setHealth(gardenErr error, containerErrs []error, at time.Time) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.health.gardenErr = gardenErr
+	p.health.containerErrs = containerErrs
+
+	if gardenErr == nil {
+		p.health.lastRefresh = at
+	}
+}
+
+// status summarises the plugin's health. It must be called with p.lock held.
+func (p *plugin) status() healthStatus {
+	h := healthStatus{
+		LastRefresh: p.health.lastRefresh,
+		Garden:      "ok",
+		ATC:         "ok",
+		CF:          "ok",
+		Containers:  "ok",
+	}
+
+	// Containers that could not be reported are only listed and do not
+	// make the plugin unhealthy, since short-lived containers commonly
+	// vanish between listing and fetching them.
+	var problems []string
+
+	if p.health.gardenErr != nil {
+		h.Garden = p.health.gardenErr.Error()
+		problems = append(problems, fmt.Sprintf("garden: %v", p.health.gardenErr))
+	}
+
+	if p.atcStatus == nil {
+		h.ATC = "disabled"
+	} else if err := p.atcStatus(); err != nil {
+		h.ATC = err.Error()
+		problems = append(problems, fmt.Sprintf("atc: %v", err))
+	}
+
+	if p.cfStatus == nil {
+		h.CF = "disabled"
+	} else if err := p.cfStatus(); err != nil {
+		h.CF = err.Error()
+		problems = append(problems, fmt.Sprintf("cf: %v", err))
+	}
+
+	if n := len(p.health.containerErrs); n > 0 {
+		h.Containers = fmt.Sprintf(
+			"failed to report %d container(s), last error: %v",
+			n,
+			p.health.containerErrs[n-1],
+		)
+
+		for _, err := range p.health.containerErrs {
+			h.ContainerErrors = append(h.ContainerErrors, err.Error())
+		}
+	}
+
+	if p.stale(time.Now()) {
+		if p.health.lastRefresh.IsZero() {
+			problems = append(problems, "no successful refresh yet")
+		} else {
+			problems = append(problems, fmt.Sprintf(
+				"stale: last successful refresh at %s",
+				p.health.lastRefresh.UTC().Format(time.RFC3339),
+			))
+		}
+	}
+
+	h.Healthy = len(problems) == 0
+	h.Status = "ok"
+	if !h.Healthy {
+		h.Status = strings.Join(problems, "; ")
+	}
+
+	return h
+}
+
+// stale returns whether the last successful refresh is too old for the
+// report to be current. It must be called with p.lock held.
+func (p *plugin) stale(now time.Time) bool {
+	return now.Sub(p.health.lastRefresh) > staleRefreshes*p.refreshInterval
+}
+
+func (p *plugin) Healthz(w http.ResponseWriter, r *http.Request) {
+	p.lock.RLock()
+	h := p.status()
+	p.lock.RUnlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	if !h.Healthy {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+
+	if err := json.NewEncoder(w).Encode(h); err != nil {
+		log.Printf("error encoding health status: %v\n", err)
+	}
+}
@@ -0,0 +1,92 @@
+package garden
+
+import (
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("health", func() {
+	var (
+		p      *plugin
+		atcErr error
+	)
+
+	BeforeEach(func() {
+		atcErr = nil
+		p = &plugin{
+			refreshInterval: time.Second,
+			atcStatus:       func() error { return atcErr },
+		}
+	})
+
+	It("is healthy after a recent successful refresh", func() {
+		p.setHealth(nil, nil, time.Now())
+
+		h := p.status()
+		Expect(h.Healthy).To(BeTrue())
+		Expect(h.Status).To(Equal("ok"))
+	})
+
+	It("is unhealthy when the last successful refresh is too old", func() {
+		p.setHealth(nil, nil, time.Now().Add(-time.Minute))
+
+		h := p.status()
+		Expect(h.Healthy).To(BeFalse())
+		Expect(h.Status).To(HavePrefix("stale: last successful refresh at"))
+	})
+
+	It("keeps the last successful refresh when garden is unreachable", func() {
+		last := time.Now()
+		p.setHealth(nil, nil, last)
+		p.setHealth(errors.New("connection refused"), nil, last.Add(time.Second))
+
+		h := p.status()
+		Expect(h.Healthy).To(BeFalse())
+		Expect(h.LastRefresh).To(Equal(last))
+		Expect(h.Garden).To(Equal("connection refused"))
+	})
+
+	It("reports containers that could not be reported without becoming unhealthy", func() {
+		p.setHealth(nil, []error{errors.New("a vanished"), errors.New("b vanished")}, time.Now())
+
+		h := p.status()
+		Expect(h.Healthy).To(BeTrue())
+		Expect(h.Containers).To(Equal("failed to report 2 container(s), last error: b vanished"))
+		Expect(h.ContainerErrors).To(Equal([]string{"a vanished", "b vanished"}))
+		Expect(h.Status).To(Equal("ok"))
+	})
+
+	It("reports ATC connectivity", func() {
+		p.setHealth(nil, nil, time.Now())
+		atcErr = errors.New("unauthorized")
+
+		h := p.status()
+		Expect(h.Healthy).To(BeFalse())
+		Expect(h.ATC).To(Equal("unauthorized"))
+		Expect(h.Status).To(Equal("atc: unauthorized"))
+	})
+
+	It("is healthy when Concourse is disabled", func() {
+		p.atcStatus = nil
+		p.setHealth(nil, nil, time.Now())
+
+		h := p.status()
+		Expect(h.Healthy).To(BeTrue())
+		Expect(h.ATC).To(Equal("disabled"))
+	})
+
+	It("reports Cloud Controller authentication failures", func() {
+		p.setHealth(nil, nil, time.Now())
+		Expect(p.status().CF).To(Equal("disabled"))
+
+		p.cfStatus = func() error { return errors.New("error requesting token from UAA: 401 Unauthorized") }
+
+		h := p.status()
+		Expect(h.Healthy).To(BeFalse())
+		Expect(h.CF).To(Equal("error requesting token from UAA: 401 Unauthorized"))
+		Expect(h.Status).To(Equal("cf: error requesting token from UAA: 401 Unauthorized"))
+	})
+})
@@ -0,0 +1,155 @@
+package garden
+
+import (
+	"sync"
+	"time"
+)
+
+const metricHistoryWindow = 5 * time.Minute
+
+// history keeps a bounded window of samples for every node metric across
+// report refreshes, so that Scope can draw trends instead of single points.
+type history struct {
+	lock   sync.Mutex
+	size   int
+	series map[string]map[string]*ring
+}
+
+func newHistory(window, interval time.Duration) *history {
+	return &history{
+		size:   historySize(window, interval),
+		series: map[string]map[string]*ring{},
+	}
+}
+
+func historySize(window, interval time.Duration) int {
+	if interval > 0 && window > interval {
+		return int(window / interval)
+	}
+
+	return 1
+}
+
+// resize changes the number of samples kept for a new refresh interval and
+// keeps the most recent samples of every window.
+func (h *history) resize(window, interval time.Duration) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	size := historySize(window, interval)
+	if size == h.size {
+		return
+	}
+
+	h.size = size
+
+	for _, series := range h.series {
+		for key, r := range series {
+			resized := newRing(size)
+			for _, s := range r.ordered() {
+				resized.add(s)
+			}
+			series[key] = resized
+		}
+	}
+}
+
+// record adds the samples of the given nodes to their windows and replaces
+// the nodes' metrics with the full windows. Windows of nodes that are no
+// longer reported are dropped.
+func (h *history) record(topologies ...map[string]nodeSpec) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	seen := map[string]bool{}
+
+	for _, nodes := range topologies {
+		for id, n := range nodes {
+			if len(n.Metrics) == 0 {
+				continue
+			}
+
+			seen[id] = true
+
+			series, found := h.series[id]
+			if !found {
+				series = map[string]*ring{}
+				h.series[id] = series
+			}
+
+			for key, m := range n.Metrics {
+				r, found := series[key]
+				if !found {
+					r = newRing(h.size)
+					series[key] = r
+				}
+
+				for _, s := range m.Samples {
+					r.add(s)
+				}
+
+				n.Metrics[key] = r.metric(m.Max)
+			}
+		}
+	}
+
+	for id := range h.series {
+		if !seen[id] {
+			delete(h.series, id)
+		}
+	}
+}
+
+type ring struct {
+	samples []sampleSpec
+	next    int
+	full    bool
+}
+
+func newRing(size int) *ring {
+	return &ring{samples: make([]sampleSpec, size)}
+}
+
+func (r *ring) add(s sampleSpec) {
+	r.samples[r.next] = s
+	r.next = (r.next + 1) % len(r.samples)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+func (r *ring) ordered() []sampleSpec {
+	if !r.full {
+		return append([]sampleSpec{}, r.samples[:r.next]...)
+	}
+
+	return append(append([]sampleSpec{}, r.samples[r.next:]...), r.samples[:r.next]...)
+}
+
+// metric returns the window as a metric. The maximum is at least limit, which
+// lets callers scale a metric against a known capacity.
+func (r *ring) metric(limit float64) metricSpec {
+	samples := r.ordered()
+	if len(samples) == 0 {
+		return metricSpec{Samples: samples, Max: limit}
+	}
+
+	m := metricSpec{
+		Samples: samples,
+		Min:     samples[0].Value,
+		Max:     limit,
+		First:   samples[0].Timestamp,
+		Last:    samples[len(samples)-1].Timestamp,
+	}
+
+	for _, s := range samples {
+		if s.Value < m.Min {
+			m.Min = s.Value
+		}
+		if s.Value > m.Max {
+			m.Max = s.Value
+		}
+	}
+
+	return m
+}
@@ -0,0 +1,84 @@
+package garden
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("history", func() {
+	var (
+		h   *history
+		now time.Time
+	)
+
+	node := func(value, max float64, at time.Time) map[string]nodeSpec {
+		return map[string]nodeSpec{
+			"node": {
+				ID: "node",
+				Metrics: map[string]metricSpec{
+					"metric": {
+						Samples: []sampleSpec{{Timestamp: at, Value: value}},
+						Max:     max,
+					},
+				},
+			},
+		}
+	}
+
+	BeforeEach(func() {
+		h = newHistory(3*time.Second, time.Second)
+		now = time.Now()
+	})
+
+	It("keeps a bounded window of samples", func() {
+		var nodes map[string]nodeSpec
+		for i, v := range []float64{4, 1, 7, 5} {
+			nodes = node(v, v, now.Add(time.Duration(i)*time.Second))
+			h.record(nodes)
+		}
+
+		m := nodes["node"].Metrics["metric"]
+		Expect(m.Samples).To(HaveLen(3))
+		Expect(m.Samples[0].Value).To(Equal(1.0))
+		Expect(m.Samples[2].Value).To(Equal(5.0))
+		Expect(m.Min).To(Equal(1.0))
+		Expect(m.Max).To(Equal(7.0))
+		Expect(m.First).To(Equal(now.Add(time.Second)))
+		Expect(m.Last).To(Equal(now.Add(3 * time.Second)))
+	})
+
+	It("keeps the reported maximum as a lower bound", func() {
+		nodes := node(2, 10, now)
+		h.record(nodes)
+
+		Expect(nodes["node"].Metrics["metric"].Max).To(Equal(10.0))
+	})
+
+	It("drops windows of nodes that are no longer reported", func() {
+		h.record(node(1, 1, now))
+		h.record(map[string]nodeSpec{})
+
+		nodes := node(2, 2, now.Add(time.Second))
+		h.record(nodes)
+
+		Expect(nodes["node"].Metrics["metric"].Samples).To(HaveLen(1))
+	})
+
+	It("keeps the most recent samples when resized", func() {
+		for i, v := range []float64{4, 1, 7} {
+			h.record(node(v, v, now.Add(time.Duration(i)*time.Second)))
+		}
+
+		h.resize(3*time.Second, 1500*time.Millisecond)
+
+		nodes := node(5, 5, now.Add(3*time.Second))
+		h.record(nodes)
+
+		m := nodes["node"].Metrics["metric"]
+		Expect(m.Samples).To(HaveLen(2))
+		Expect(m.Samples[0].Value).To(Equal(7.0))
+		Expect(m.Samples[1].Value).To(Equal(5.0))
+	})
+})
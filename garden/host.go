@@ -0,0 +1,113 @@
+package garden
+
+import (
+	"fmt"
+
+	"code.cloudfoundry.org/garden"
+)
+
+type hostUsage struct {
+	containers  int
+	memoryBytes uint64
+	diskBytes   uint64
+	cpuSeconds  float64
+	rxBytes     uint64
+	txBytes     uint64
+	cpuPercent  float64
+	rxPerSec    float64
+	txPerSec    float64
+}
+
+func (u *hostUsage) add(m garden.Metrics) {
+	u.containers++
+	u.memoryBytes += m.MemoryStat.TotalUsageTowardLimit
+	u.diskBytes += m.DiskStat.TotalBytesUsed
+	u.cpuSeconds += cpuSeconds(m.CPUStat.Usage)
+	u.rxBytes += m.NetworkStat.RxBytes
+	u.txBytes += m.NetworkStat.TxBytes
+}
+
+func (u *hostUsage) addRates(r rates) {
+	u.cpuPercent += r.cpuPercent
+	u.rxPerSec += r.rxPerSec
+	u.txPerSec += r.txPerSec
+}
+
+// AddHost describes the local host with Garden's capacity and the summed
+// usage of all containers added to the report so far. If the capacity could
+// not be fetched, it is nil and only the usage is reported.
+func (r *report) AddHost(capacity *garden.Capacity) {
+	id := hostNodeID(r.hostname)
+
+	n := nodeSpec{
+		ID:       id,
+		Topology: "host",
+		Latest: map[string]latestSpec{
+			"host_name":           latest(r.hostname),
+			HostContainerCapacity: latest(containerCapacity(r.usage.containers, 0)),
+		},
+		Metrics: map[string]metricSpec{
+			HostContainers:  metric(float64(r.usage.containers)),
+			HostMemoryUsage: metric(float64(r.usage.memoryBytes)),
+			HostDiskUsage:   metric(float64(r.usage.diskBytes)),
+			HostCPUUsage:    metric(r.usage.cpuSeconds),
+			HostNetworkRx:   metric(float64(r.usage.rxBytes)),
+			HostNetworkTx:   metric(float64(r.usage.txBytes)),
+
+			HostCPUPercent:    metric(r.usage.cpuPercent),
+			HostNetworkRxRate: metric(r.usage.rxPerSec),
+			HostNetworkTxRate: metric(r.usage.txPerSec),
+		},
+	}
+
+	if capacity != nil {
+		n.Latest[HostMemoryCapacity] = latest(byteSize(capacity.MemoryInBytes))
+		n.Latest[HostDiskCapacity] = latest(byteSize(capacity.DiskInBytes))
+		n.Latest[HostMaxContainers] = latest(fmt.Sprintf("%d", capacity.MaxContainers))
+		n.Latest[HostContainerCapacity] = latest(containerCapacity(r.usage.containers, capacity.MaxContainers))
+
+		n.Metrics[HostContainers] = metricWithMax(float64(r.usage.containers), float64(capacity.MaxContainers))
+		n.Metrics[HostMemoryUsage] = metricWithMax(float64(r.usage.memoryBytes), float64(capacity.MemoryInBytes))
+		n.Metrics[HostDiskUsage] = metricWithMax(float64(r.usage.diskBytes), float64(capacity.DiskInBytes))
+	}
+
+	r.Host.Nodes[n.ID] = n
+}
+
+func containerCapacity(count int, max uint64) string {
+	if max == 0 {
+		return fmt.Sprintf("%d", count)
+	}
+
+	return fmt.Sprintf("%d/%d (%.1f%%)", count, max, float64(count)*100/float64(max))
+}
+
+func byteSize(b uint64) string {
+	const unit = 1024
+	if b < unit {
+		return fmt.Sprintf("%d B", b)
+	}
+
+	div, exp := uint64(unit), 0
+	for n := b / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(b)/float64(div), "KMGTPE"[exp])
+}
+
+func hostNodeID(hostname string) string {
+	return fmt.Sprintf("%s;<host>", hostname)
+}
+
+func newHost() topologySpec {
+	return topologySpec{
+		Label:             "host",
+		LabelPlural:       "hosts",
+		Shape:             "circle",
+		MetadataTemplates: hostMetadataTemplates,
+		MetricTemplates:   hostMetricTemplates,
+		Nodes:             map[string]nodeSpec{},
+	}
+}
@@ -0,0 +1,145 @@
+package garden
+
+import (
+	"errors"
+	"time"
+
+	"code.cloudfoundry.org/garden"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// capacityClient returns a fixed capacity, or fails if err is set.
+type capacityClient struct {
+	*fakeClient
+	capacity garden.Capacity
+	err      error
+}
+
+func (c *capacityClient) Capacity() (garden.Capacity, error) {
+	return c.capacity, c.err
+}
+
+var _ = Describe("host", func() {
+	var r report
+
+	addContainer := func(handle string, memory, disk uint64) {
+		Expect(r.AddNode(containerEntry{
+			container: fakeContainer{handle: handle},
+			metrics: garden.Metrics{
+				MemoryStat:  garden.ContainerMemoryStat{TotalUsageTowardLimit: memory},
+				DiskStat:    garden.ContainerDiskStat{TotalBytesUsed: disk},
+				CPUStat:     garden.ContainerCPUStat{Usage: uint64(2 * time.Second)},
+				NetworkStat: garden.ContainerNetworkStat{RxBytes: 10, TxBytes: 20},
+			},
+		})).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		r = newReport("host", nil, propertyFilter{}, processSource{}, newCounters(1), newEventLog())
+		addContainer("a", 1024, 4096)
+		addContainer("b", 2048, 8192)
+	})
+
+	It("sums the usage of all containers", func() {
+		r.AddHost(&garden.Capacity{MemoryInBytes: 1 << 30, DiskInBytes: 1 << 40, MaxContainers: 8})
+
+		n := r.Host.Nodes["host;<host>"]
+		Expect(n.Metrics[HostContainers].Samples[0].Value).To(Equal(2.0))
+		Expect(n.Metrics[HostMemoryUsage].Samples[0].Value).To(Equal(3072.0))
+		Expect(n.Metrics[HostDiskUsage].Samples[0].Value).To(Equal(12288.0))
+		Expect(n.Metrics[HostCPUUsage].Samples[0].Value).To(Equal(4.0))
+		Expect(n.Metrics[HostNetworkRx].Samples[0].Value).To(Equal(20.0))
+		Expect(n.Metrics[HostNetworkTx].Samples[0].Value).To(Equal(40.0))
+	})
+
+	It("reports Garden's capacity as the maximum of the usage", func() {
+		r.AddHost(&garden.Capacity{MemoryInBytes: 1 << 30, DiskInBytes: 1 << 40, MaxContainers: 8})
+
+		n := r.Host.Nodes["host;<host>"]
+		Expect(n.Latest[HostMemoryCapacity].Value).To(Equal("1.0 GiB"))
+		Expect(n.Latest[HostDiskCapacity].Value).To(Equal("1.0 TiB"))
+		Expect(n.Latest[HostMaxContainers].Value).To(Equal("8"))
+		Expect(n.Latest[HostContainerCapacity].Value).To(Equal("2/8 (25.0%)"))
+		Expect(n.Metrics[HostContainers].Max).To(Equal(8.0))
+		Expect(n.Metrics[HostMemoryUsage].Max).To(Equal(float64(1 << 30)))
+		Expect(n.Metrics[HostDiskUsage].Max).To(Equal(float64(1 << 40)))
+	})
+
+	It("does not report a maximum below the usage", func() {
+		r.AddHost(&garden.Capacity{MemoryInBytes: 1024, MaxContainers: 1})
+
+		n := r.Host.Nodes["host;<host>"]
+		Expect(n.Metrics[HostContainers].Max).To(Equal(2.0))
+		Expect(n.Metrics[HostMemoryUsage].Max).To(Equal(3072.0))
+		Expect(n.Metrics[HostDiskUsage].Max).To(Equal(12288.0))
+	})
+
+	It("reports only the usage when the capacity is unknown", func() {
+		r.AddHost(nil)
+
+		n := r.Host.Nodes["host;<host>"]
+		Expect(n.Latest).ToNot(HaveKey(HostMemoryCapacity))
+		Expect(n.Latest).ToNot(HaveKey(HostDiskCapacity))
+		Expect(n.Latest).ToNot(HaveKey(HostMaxContainers))
+		Expect(n.Latest[HostContainerCapacity].Value).To(Equal("2"))
+		Expect(n.Metrics[HostMemoryUsage].Samples[0].Value).To(Equal(3072.0))
+		Expect(n.Metrics[HostMemoryUsage].Max).To(Equal(3072.0))
+	})
+
+	Describe("refresh", func() {
+		var (
+			client *capacityClient
+			p      *plugin
+		)
+
+		BeforeEach(func() {
+			client = &capacityClient{
+				fakeClient: &fakeClient{
+					hang:       make(chan struct{}),
+					containers: []garden.Container{fakeContainer{handle: "a"}},
+					infos:      map[string]garden.ContainerInfoEntry{"a": {}},
+					metrics: map[string]garden.ContainerMetricsEntry{"a": {Metrics: garden.Metrics{
+						MemoryStat: garden.ContainerMemoryStat{TotalUsageTowardLimit: 1024},
+					}}},
+				},
+				capacity: garden.Capacity{MemoryInBytes: 4096, MaxContainers: 4},
+			}
+
+			p = &plugin{
+				hostname: "host",
+				registry: newRegistry(client, client, 1, time.Second, time.Second),
+				counters: newCounters(1),
+				events:   newEventLog(),
+				history:  newHistory(time.Minute, time.Second),
+			}
+		})
+
+		AfterEach(func() {
+			close(client.hang)
+		})
+
+		It("describes the host with Garden's capacity", func() {
+			p.refresh()
+
+			n := p.report.Host.Nodes["host;<host>"]
+			Expect(n.Latest[HostContainerCapacity].Value).To(Equal("1/4 (25.0%)"))
+			Expect(n.Metrics[HostMemoryUsage].Max).To(Equal(4096.0))
+		})
+
+		It("still describes the host's usage when the capacity cannot be fetched", func() {
+			client.err = errors.New("connection refused")
+
+			_, err := p.registry.capacity()
+			Expect(err).To(MatchError("error fetching capacity: connection refused"))
+
+			p.refresh()
+
+			Expect(p.report.Host.Nodes).To(HaveKey("host;<host>"))
+			n := p.report.Host.Nodes["host;<host>"]
+			Expect(n.Latest).ToNot(HaveKey(HostMemoryCapacity))
+			Expect(n.Latest[HostContainerCapacity].Value).To(Equal("1"))
+			Expect(n.Metrics[HostMemoryUsage].Samples[0].Value).To(Equal(1024.0))
+		})
+	})
+})
@@ -0,0 +1,85 @@
+package garden
+
+import (
+	"fmt"
+
+	"code.cloudfoundry.org/garden"
+)
+
+type limits struct {
+	memory    garden.MemoryLimits
+	cpu       garden.CPULimits
+	disk      garden.DiskLimits
+	bandwidth garden.BandwidthLimits
+}
+
+// containerLimits fetches the current resource limits of a container. Limits
+// only enrich the report, so a limit that cannot be fetched is left unset,
+// which is treated as unlimited, and the first error is returned alongside
+// the limits that could be fetched.
+func containerLimits(c garden.Container) (limits, error) {
+	var (
+		l        limits
+		firstErr error
+	)
+
+	check := func(err error) bool {
+		if err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("error fetching limits of container %q: %v", c.Handle(), err)
+		}
+		return err == nil
+	}
+
+	if memory, err := c.CurrentMemoryLimits(); check(err) {
+		l.memory = memory
+	}
+
+	if cpu, err := c.CurrentCPULimits(); check(err) {
+		l.cpu = cpu
+	}
+
+	if disk, err := c.CurrentDiskLimits(); check(err) {
+		l.disk = disk
+	}
+
+	if bandwidth, err := c.CurrentBandwidthLimits(); check(err) {
+		l.bandwidth = bandwidth
+	}
+
+	return l, firstErr
+}
+
+func (l limits) latest() map[string]latestSpec {
+	return map[string]latestSpec{
+		ContainerMemoryLimit:    latest(limitSize(l.memory.LimitInBytes)),
+		ContainerCPUShares:      latest(limitCount(l.cpu.LimitInShares)),
+		ContainerDiskLimit:      latest(limitSize(l.disk.ByteHard)),
+		ContainerInodeLimit:     latest(limitCount(l.disk.InodeHard)),
+		ContainerBandwidthLimit: latest(limitRate(l.bandwidth.RateInBytesPerSecond)),
+		ContainerBandwidthBurst: latest(limitRate(l.bandwidth.BurstRateInBytesPerSecond)),
+	}
+}
+
+func limitSize(b uint64) string {
+	if b == 0 {
+		return "unlimited"
+	}
+
+	return byteSize(b)
+}
+
+func limitRate(b uint64) string {
+	if b == 0 {
+		return "unlimited"
+	}
+
+	return fmt.Sprintf("%s/s", byteSize(b))
+}
+
+func limitCount(n uint64) string {
+	if n == 0 {
+		return "unlimited"
+	}
+
+	return fmt.Sprintf("%d", n)
+}
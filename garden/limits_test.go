@@ -0,0 +1,143 @@
+package garden
+
+import (
+	"errors"
+	"time"
+
+	"code.cloudfoundry.org/garden"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// limitedContainer reports fixed limits, and fails to fetch its CPU limit if
+// cpuErr is set.
+type limitedContainer struct {
+	fakeContainer
+	limits limits
+	cpuErr error
+}
+
+func (c limitedContainer) CurrentMemoryLimits() (garden.MemoryLimits, error) {
+	return c.limits.memory, nil
+}
+
+func (c limitedContainer) CurrentCPULimits() (garden.CPULimits, error) {
+	return c.limits.cpu, c.cpuErr
+}
+
+func (c limitedContainer) CurrentDiskLimits() (garden.DiskLimits, error) {
+	return c.limits.disk, nil
+}
+
+func (c limitedContainer) CurrentBandwidthLimits() (garden.BandwidthLimits, error) {
+	return c.limits.bandwidth, nil
+}
+
+var _ = Describe("limits", func() {
+	var (
+		r         report
+		container limitedContainer
+		metrics   garden.Metrics
+	)
+
+	node := func() nodeSpec {
+		return r.Container.Nodes["a;<container>"]
+	}
+
+	addNode := func() {
+		l, err := containerLimits(container)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(r.AddNode(containerEntry{container: container, metrics: metrics, limits: l})).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		counters := newCounters(1)
+		counters.observe("a", garden.Metrics{}, time.Now().Add(-time.Second))
+		counters.rotate()
+
+		r = newReport("host", nil, propertyFilter{}, processSource{}, counters, newEventLog())
+
+		container = limitedContainer{
+			fakeContainer: fakeContainer{handle: "a"},
+			limits: limits{
+				memory:    garden.MemoryLimits{LimitInBytes: 512 * 1024 * 1024},
+				cpu:       garden.CPULimits{LimitInShares: 1024},
+				disk:      garden.DiskLimits{ByteHard: 1024 * 1024 * 1024, InodeHard: 200000},
+				bandwidth: garden.BandwidthLimits{RateInBytesPerSecond: 1024 * 1024, BurstRateInBytesPerSecond: 2 * 1024 * 1024},
+			},
+		}
+
+		metrics = garden.Metrics{
+			MemoryStat:  garden.ContainerMemoryStat{TotalUsageTowardLimit: 384 * 1024 * 1024},
+			DiskStat:    garden.ContainerDiskStat{TotalBytesUsed: 256 * 1024 * 1024},
+			CPUStat:     garden.ContainerCPUStat{Usage: uint64(500 * time.Millisecond)},
+			NetworkStat: garden.ContainerNetworkStat{RxBytes: 1024, TxBytes: 2048},
+		}
+	})
+
+	It("shows the limits as metadata", func() {
+		addNode()
+
+		n := node()
+		Expect(n.Latest[ContainerMemoryLimit].Value).To(Equal("512.0 MiB"))
+		Expect(n.Latest[ContainerCPUShares].Value).To(Equal("1024"))
+		Expect(n.Latest[ContainerDiskLimit].Value).To(Equal("1.0 GiB"))
+		Expect(n.Latest[ContainerInodeLimit].Value).To(Equal("200000"))
+		Expect(n.Latest[ContainerBandwidthLimit].Value).To(Equal("1.0 MiB/s"))
+		Expect(n.Latest[ContainerBandwidthBurst].Value).To(Equal("2.0 MiB/s"))
+	})
+
+	It("reports memory usage in bytes", func() {
+		addNode()
+
+		Expect(node().Metrics[MemoryUsage].Samples[0].Value).To(Equal(float64(384 * 1024 * 1024)))
+		Expect(r.samples[0].values[promMemoryUsage]).To(Equal(float64(384 * 1024 * 1024)))
+		Expect(r.samples[0].values[promMemoryLimit]).To(Equal(float64(512 * 1024 * 1024)))
+	})
+
+	It("uses the limits as the maximum of the usage", func() {
+		addNode()
+
+		n := node()
+		Expect(n.Metrics[MemoryUsage].Max).To(Equal(float64(512 * 1024 * 1024)))
+		Expect(n.Metrics[DiskUsage].Max).To(Equal(float64(1024 * 1024 * 1024)))
+		Expect(n.Metrics[NetworkRxRate].Max).To(Equal(float64(1024 * 1024)))
+		Expect(n.Metrics[NetworkTxRate].Max).To(Equal(float64(1024 * 1024)))
+		Expect(n.Metrics[CPUPercent].Max).To(Equal(100.0))
+	})
+
+	It("reports memory usage relative to the limit", func() {
+		addNode()
+
+		usage := node().Metrics[MemoryLimitUsage]
+		Expect(usage.Samples[0].Value).To(Equal(75.0))
+		Expect(usage.Max).To(Equal(100.0))
+	})
+
+	It("treats containers without limits as unlimited", func() {
+		container.limits = limits{}
+		addNode()
+
+		n := node()
+		Expect(n.Latest[ContainerMemoryLimit].Value).To(Equal("unlimited"))
+		Expect(n.Latest[ContainerCPUShares].Value).To(Equal("unlimited"))
+		Expect(n.Latest[ContainerBandwidthLimit].Value).To(Equal("unlimited"))
+		Expect(n.Metrics[MemoryUsage].Samples[0].Value).To(Equal(float64(384 * 1024 * 1024)))
+		Expect(n.Metrics[MemoryUsage].Max).To(Equal(float64(384 * 1024 * 1024)))
+		Expect(n.Metrics[DiskUsage].Max).To(Equal(float64(256 * 1024 * 1024)))
+		Expect(n.Metrics[NetworkRxRate].Max).To(Equal(n.Metrics[NetworkRxRate].Samples[0].Value))
+		Expect(n.Metrics).ToNot(HaveKey(MemoryLimitUsage))
+		Expect(r.samples[0].values).ToNot(HaveKey(promMemoryLimit))
+		Expect(r.samples[0].values).ToNot(HaveKey(promDiskLimit))
+	})
+
+	It("keeps the limits that could be fetched", func() {
+		container.cpuErr = errors.New("connection reset")
+
+		l, err := containerLimits(container)
+		Expect(err).To(MatchError(`error fetching limits of container "a": connection reset`))
+		Expect(l.memory.LimitInBytes).To(Equal(uint64(512 * 1024 * 1024)))
+		Expect(l.cpu.LimitInShares).To(BeZero())
+		Expect(l.bandwidth.RateInBytesPerSecond).To(Equal(uint64(1024 * 1024)))
+	})
+})
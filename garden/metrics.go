@@ -0,0 +1,213 @@
+package garden
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	promCPUSeconds  = "garden_container_cpu_seconds_total"
+	promCPUPercent  = "garden_container_cpu_usage_percent"
+	promMemoryUsage = "garden_container_memory_usage_bytes"
+	promMemoryLimit = "garden_container_memory_limit_bytes"
+	promDiskUsage   = "garden_container_disk_usage_bytes"
+	promDiskLimit   = "garden_container_disk_limit_bytes"
+	promNetworkRx   = "garden_container_network_receive_bytes_total"
+	promNetworkTx   = "garden_container_network_transmit_bytes_total"
+	promStale       = "garden_container_stale"
+	promUp          = "garden_up"
+	promLastRefresh = "garden_last_refresh_timestamp_seconds"
+
+	promLabelHandle = "handle"
+	promLabelWorker = "worker"
+
+	promCounter = "counter"
+	promGauge   = "gauge"
+
+	promContentType = "text/plain; version=0.0.4; charset=utf-8"
+)
+
+type promMetric struct {
+	name string
+	help string
+	kind string
+}
+
+// promMetrics are the container metrics exported to Prometheus, in the order
+// they are written.
+var promMetrics = []promMetric{
+	{promCPUSeconds, "Total CPU time consumed by the container in seconds.", promCounter},
+	{promCPUPercent, "CPU usage of the container since the previous refresh.", promGauge},
+	{promMemoryUsage, "Memory used by the container towards its limit in bytes.", promGauge},
+	{promMemoryLimit, "Memory limit of the container in bytes.", promGauge},
+	{promDiskUsage, "Disk space used by the container in bytes.", promGauge},
+	{promDiskLimit, "Disk limit of the container in bytes.", promGauge},
+	{promNetworkRx, "Total bytes received by the container.", promCounter},
+	{promNetworkTx, "Total bytes transmitted by the container.", promCounter},
+	{promStale, "Whether the container's data could not be refreshed and is stale.", promGauge},
+}
+
+// pluginSample holds the values of the plugin's own Prometheus metrics.
+type pluginSample struct {
+	up          bool
+	lastRefresh time.Time
+}
+
+// containerSample holds the values of a container's Prometheus metrics.
+type containerSample struct {
+	labels map[string]string
+	values map[string]float64
+}
+
+// addSample records the Prometheus metrics of a container, labelled with its
+// handle, this worker and the labels of its enrichment.
+func (r *report) addSample(e containerEntry, labels map[string]string, rates rates, hasRates bool) {
+	s := containerSample{
+		labels: map[string]string{
+			promLabelHandle: e.container.Handle(),
+			promLabelWorker: r.hostname,
+		},
+		values: map[string]float64{
+			promCPUSeconds:  cpuSeconds(e.metrics.CPUStat.Usage),
+			promMemoryUsage: float64(e.metrics.MemoryStat.TotalUsageTowardLimit),
+			promDiskUsage:   float64(e.metrics.DiskStat.TotalBytesUsed),
+			promNetworkRx:   float64(e.metrics.NetworkStat.RxBytes),
+			promNetworkTx:   float64(e.metrics.NetworkStat.TxBytes),
+			promStale:       0,
+		},
+	}
+
+	for k, v := range labels {
+		if _, found := s.labels[k]; !found {
+			s.labels[k] = v
+		}
+	}
+
+	if hasRates {
+		s.values[promCPUPercent] = rates.cpuPercent
+	}
+
+	if l := e.limits.memory.LimitInBytes; l > 0 {
+		s.values[promMemoryLimit] = float64(l)
+	}
+
+	if l := e.limits.disk.ByteHard; l > 0 {
+		s.values[promDiskLimit] = float64(l)
+	}
+
+	if e.stale {
+		s.values[promStale] = 1
+	}
+
+	r.samples = append(r.samples, s)
+}
+
+// Metrics serves the container metrics of the last report in the Prometheus
+// text format, together with whether Garden is reachable and when the report
+// was last refreshed. Once the report is stale, all containers are marked as
+// stale.
+func (p *plugin) Metrics(w http.ResponseWriter, r *http.Request) {
+	p.lock.RLock()
+	samples := p.report.samples
+	plugin := pluginSample{
+		up:          p.health.gardenErr == nil && !p.health.lastRefresh.IsZero(),
+		lastRefresh: p.health.lastRefresh,
+	}
+	stale := p.stale(time.Now())
+	p.lock.RUnlock()
+
+	if stale {
+		samples = markStale(samples)
+	}
+
+	w.Header().Set("Content-Type", promContentType)
+
+	if err := writeMetrics(w, plugin, samples); err != nil {
+		log.Printf("error writing metrics: %v\n", err)
+	}
+}
+
+// markStale returns copies of samples that are marked as stale.
+func markStale(samples []containerSample) []containerSample {
+	marked := make([]containerSample, len(samples))
+	for i, s := range samples {
+		values := make(map[string]float64, len(s.values))
+		for k, v := range s.values {
+			values[k] = v
+		}
+		values[promStale] = 1
+
+		marked[i] = containerSample{labels: s.labels, values: values}
+	}
+
+	return marked
+}
+
+func writeMetrics(w io.Writer, plugin pluginSample, samples []containerSample) error {
+	sorted := make([]containerSample, len(samples))
+	copy(sorted, samples)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].labels[promLabelHandle] < sorted[j].labels[promLabelHandle]
+	})
+
+	b := bufio.NewWriter(w)
+
+	up := 0
+	if plugin.up {
+		up = 1
+	}
+
+	fmt.Fprintf(b, "# HELP %s Whether the last refresh reached the Garden server.\n", promUp)
+	fmt.Fprintf(b, "# TYPE %s %s\n", promUp, promGauge)
+	fmt.Fprintf(b, "%s %d\n", promUp, up)
+
+	if !plugin.lastRefresh.IsZero() {
+		fmt.Fprintf(b, "# HELP %s Time of the last successful refresh in seconds since the epoch.\n", promLastRefresh)
+		fmt.Fprintf(b, "# TYPE %s %s\n", promLastRefresh, promGauge)
+		fmt.Fprintf(b, "%s %s\n", promLastRefresh, strconv.FormatFloat(float64(plugin.lastRefresh.UnixNano())/1e9, 'f', 3, 64))
+	}
+
+	for _, m := range promMetrics {
+		fmt.Fprintf(b, "# HELP %s %s\n", m.name, m.help)
+		fmt.Fprintf(b, "# TYPE %s %s\n", m.name, m.kind)
+
+		for _, s := range sorted {
+			if v, found := s.values[m.name]; found {
+				fmt.Fprintf(b, "%s{%s} %s\n", m.name, promLabels(s.labels), strconv.FormatFloat(v, 'g', -1, 64))
+			}
+		}
+	}
+
+	return b.Flush()
+}
+
+// promLabels formats labels with non-empty values, sorted by name.
+func promLabels(labels map[string]string) string {
+	names := make([]string, 0, len(labels))
+	for k, v := range labels {
+		if v != "" {
+			names = append(names, k)
+		}
+	}
+	sort.Strings(names)
+
+	pairs := make([]string, len(names))
+	for i, k := range names {
+		pairs[i] = fmt.Sprintf(`%s="%s"`, k, escapeLabelValue(labels[k]))
+	}
+
+	return strings.Join(pairs, ",")
+}
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+
+func escapeLabelValue(v string) string {
+	return labelValueEscaper.Replace(v)
+}
@@ -0,0 +1,96 @@
+package garden
+
+import (
+	"bytes"
+	"errors"
+	"net/http/httptest"
+	"time"
+
+	"code.cloudfoundry.org/garden"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("metrics", func() {
+	var r report
+
+	BeforeEach(func() {
+		enricher := fakeEnricher{
+			"a": {Labels: map[string]string{"pipeline": "main", "job": "unit", "step": `say "hi"`, "team": "", "handle": "other"}},
+		}
+		r = newReport("worker-1", chain{enricher}, propertyFilter{}, processSource{}, newCounters(1), newEventLog())
+
+		var l limits
+		l.memory.LimitInBytes = 2048
+
+		Expect(r.AddNode(containerEntry{
+			container: fakeContainer{handle: "a"},
+			metrics: garden.Metrics{
+				MemoryStat:  garden.ContainerMemoryStat{TotalUsageTowardLimit: 1024},
+				CPUStat:     garden.ContainerCPUStat{Usage: 1500000000},
+				NetworkStat: garden.ContainerNetworkStat{RxBytes: 10, TxBytes: 20},
+			},
+			limits: l,
+		})).To(Succeed())
+
+		Expect(r.AddNode(containerEntry{
+			container: fakeContainer{handle: "b"},
+			stale:     true,
+		})).To(Succeed())
+	})
+
+	It("writes container metrics in the Prometheus text format", func() {
+		var b bytes.Buffer
+		Expect(writeMetrics(&b, pluginSample{}, r.samples)).To(Succeed())
+
+		a := `{handle="a",job="unit",pipeline="main",step="say \"hi\"",worker="worker-1"}`
+		Expect(b.String()).To(ContainSubstring("# TYPE garden_container_cpu_seconds_total counter\n"))
+		Expect(b.String()).To(ContainSubstring("garden_container_cpu_seconds_total" + a + " 1.5\n"))
+		Expect(b.String()).To(ContainSubstring("garden_container_memory_usage_bytes" + a + " 1024\n"))
+		Expect(b.String()).To(ContainSubstring("garden_container_memory_limit_bytes" + a + " 2048\n"))
+		Expect(b.String()).To(ContainSubstring("garden_container_network_receive_bytes_total" + a + " 10\n"))
+		Expect(b.String()).To(ContainSubstring("garden_container_network_transmit_bytes_total" + a + " 20\n"))
+		Expect(b.String()).To(ContainSubstring(`garden_container_stale{handle="a",job="unit",pipeline="main",step="say \"hi\"",worker="worker-1"} 0` + "\n"))
+		Expect(b.String()).To(ContainSubstring(`garden_container_stale{handle="b",worker="worker-1"} 1` + "\n"))
+		Expect(b.String()).ToNot(ContainSubstring(`garden_container_memory_limit_bytes{handle="b"`))
+	})
+
+	It("serves the metrics of the current report", func() {
+		p := &plugin{report: r, refreshInterval: time.Second}
+		p.setHealth(nil, nil, time.Now())
+
+		w := httptest.NewRecorder()
+		p.Metrics(w, httptest.NewRequest("GET", "/metrics", nil))
+
+		Expect(w.Header().Get("Content-Type")).To(HavePrefix("text/plain; version=0.0.4"))
+		Expect(w.Body.String()).To(ContainSubstring("garden_up 1\n"))
+		Expect(w.Body.String()).To(ContainSubstring("# TYPE garden_last_refresh_timestamp_seconds gauge\n"))
+		Expect(w.Body.String()).To(ContainSubstring(`garden_container_disk_usage_bytes{handle="b",worker="worker-1"} 0`))
+		Expect(w.Body.String()).To(ContainSubstring(`garden_container_stale{handle="a",job="unit",pipeline="main",step="say \"hi\"",worker="worker-1"} 0`))
+	})
+
+	It("reports when garden is down and the report is stale", func() {
+		p := &plugin{report: r, refreshInterval: time.Second}
+		p.setHealth(nil, nil, time.Unix(1500000000, 500000000))
+		p.setHealth(errors.New("connection refused"), nil, time.Now())
+
+		w := httptest.NewRecorder()
+		p.Metrics(w, httptest.NewRequest("GET", "/metrics", nil))
+
+		Expect(w.Body.String()).To(ContainSubstring("garden_up 0\n"))
+		Expect(w.Body.String()).To(ContainSubstring("garden_last_refresh_timestamp_seconds 1500000000.500\n"))
+		Expect(w.Body.String()).To(ContainSubstring(`garden_container_stale{handle="a",job="unit",pipeline="main",step="say \"hi\"",worker="worker-1"} 1`))
+		Expect(w.Body.String()).To(ContainSubstring(`garden_container_memory_usage_bytes{handle="a",job="unit",pipeline="main",step="say \"hi\"",worker="worker-1"} 1024`))
+		Expect(r.samples[0].values[promStale]).To(BeZero())
+	})
+
+	It("reports garden as down before the first refresh", func() {
+		p := &plugin{refreshInterval: time.Second}
+
+		w := httptest.NewRecorder()
+		p.Metrics(w, httptest.NewRequest("GET", "/metrics", nil))
+
+		Expect(w.Body.String()).To(ContainSubstring("garden_up 0\n"))
+		Expect(w.Body.String()).ToNot(ContainSubstring("garden_last_refresh_timestamp_seconds"))
+	})
+})
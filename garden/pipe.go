@@ -0,0 +1,168 @@
+package garden
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"sync"
+
+	"code.cloudfoundry.org/garden"
+)
+
+const (
+	ExecShell     = "garden_exec_shell"
+	ResizeExecTTY = "garden_resize_exec_tty"
+
+	execShellCommand = "TERM=xterm exec $( (type getent > /dev/null 2>&1 && getent passwd root | cut -d: -f7 2>/dev/null) || echo /bin/sh)"
+)
+
+type pipes struct {
+	lock      sync.Mutex
+	appURL    string
+	token     string
+	processes map[string]garden.Process
+}
+
+func newPipes(appURL, token string) *pipes {
+	return &pipes{
+		appURL:    appURL,
+		token:     token,
+		processes: map[string]garden.Process{},
+	}
+}
+
+// configure sets the Scope app that new pipes are created with.
+func (p *pipes) configure(appURL, token string) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.appURL = appURL
+	p.token = token
+}
+
+// exec starts a shell in the given container and connects its stdio to a
+// newly created Scope pipe. It returns the ID of that pipe.
+func (p *pipes) exec(c garden.Container) (string, error) {
+	id, err := newPipeID()
+	if err != nil {
+		return "", err
+	}
+
+	p.lock.Lock()
+	pipeURL, header := p.pipeURL(id), p.header()
+	p.lock.Unlock()
+
+	ws, err := dialWebsocket(pipeURL, header)
+	if err != nil {
+		return "", fmt.Errorf("error connecting to pipe %q: %v", id, err)
+	}
+
+	process, err := c.Run(
+		garden.ProcessSpec{
+			Path: "/bin/sh",
+			Args: []string{"-c", execShellCommand},
+			User: "root",
+			TTY:  &garden.TTYSpec{},
+		},
+		garden.ProcessIO{
+			Stdin:  ws,
+			Stdout: ws,
+			Stderr: ws,
+		},
+	)
+	if err != nil {
+		ws.Close()
+		return "", fmt.Errorf("error running shell in container %q: %v", c.Handle(), err)
+	}
+
+	p.lock.Lock()
+	p.processes[id] = process
+	p.lock.Unlock()
+
+	go func() {
+		if _, err := process.Wait(); err != nil {
+			log.Printf("error waiting for shell in container %q: %v\n", c.Handle(), err)
+		}
+
+		p.lock.Lock()
+		delete(p.processes, id)
+		p.lock.Unlock()
+
+		ws.Close()
+	}()
+
+	return id, nil
+}
+
+func (p *pipes) resize(id string, width, height int) error {
+	p.lock.Lock()
+	process, found := p.processes[id]
+	p.lock.Unlock()
+
+	if !found {
+		return fmt.Errorf("unknown pipe %q", id)
+	}
+
+	return process.SetTTY(garden.TTYSpec{
+		WindowSize: &garden.WindowSize{
+			Columns: width,
+			Rows:    height,
+		},
+	})
+}
+
+// pipeURL must be called with p.lock held.
+func (p *pipes) pipeURL(id string) string {
+	u, err := url.Parse(p.appURL)
+	if err != nil {
+		return ""
+	}
+
+	switch u.Scheme {
+	case "https":
+		u.Scheme = "wss"
+	default:
+		u.Scheme = "ws"
+	}
+
+	u.Path = path.Join(u.Path, "api", "pipe", id, "probe")
+	return u.String()
+}
+
+// header must be called with p.lock held.
+func (p *pipes) header() http.Header {
+	h := http.Header{}
+	if p.token != "" {
+		h.Set("Authorization", fmt.Sprintf("Scope-Probe token=%s", p.token))
+	}
+
+	return h
+}
+
+func newPipeID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+func ttySize(args map[string]string) (int, int, error) {
+	width, err := strconv.Atoi(args["width"])
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid tty width %q", args["width"])
+	}
+
+	height, err := strconv.Atoi(args["height"])
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid tty height %q", args["height"])
+	}
+
+	return width, height, nil
+}
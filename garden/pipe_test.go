@@ -0,0 +1,174 @@
+package garden
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/garden"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type fakeProcess struct {
+	garden.Process
+	lock   sync.Mutex
+	ttys   []garden.TTYSpec
+	exited chan struct{}
+}
+
+func (p *fakeProcess) Wait() (int, error) {
+	<-p.exited
+	return 0, nil
+}
+
+func (p *fakeProcess) SetTTY(tty garden.TTYSpec) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.ttys = append(p.ttys, tty)
+	return nil
+}
+
+func (p *fakeProcess) ttySpecs() []garden.TTYSpec {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	return p.ttys
+}
+
+// shellContainer runs a fake shell that echoes every line of its stdin to
+// its stdout with a prefix.
+type shellContainer struct {
+	fakeContainer
+	spec    chan garden.ProcessSpec
+	process *fakeProcess
+}
+
+func (c *shellContainer) Run(spec garden.ProcessSpec, pio garden.ProcessIO) (garden.Process, error) {
+	c.spec <- spec
+
+	go func() {
+		defer GinkgoRecover()
+
+		lines := bufio.NewScanner(pio.Stdin)
+		for lines.Scan() {
+			io.WriteString(pio.Stdout, "$ "+lines.Text())
+		}
+	}()
+
+	return c.process, nil
+}
+
+var _ = Describe("pipes", func() {
+	var (
+		scope     *fakeScope
+		server    *httptest.Server
+		container *shellContainer
+		p         *plugin
+	)
+
+	BeforeEach(func() {
+		scope = &fakeScope{header: make(chan http.Header, 1)}
+		server = httptest.NewServer(scope)
+
+		container = &shellContainer{
+			fakeContainer: fakeContainer{handle: "handle"},
+			spec:          make(chan garden.ProcessSpec, 1),
+			process:       &fakeProcess{exited: make(chan struct{})},
+		}
+		client := &lookupClient{containers: map[string]garden.Container{"handle": container}}
+
+		p = &plugin{
+			registry: newRegistry(client, client, 1, time.Second, time.Second),
+			pipes:    newPipes(server.URL, "secret"),
+		}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("bridges a shell in the container to a Scope pipe", func() {
+		output := make(chan []byte, 1)
+		scope.serve = func(conn net.Conn, r *bufio.Reader) {
+			conn.Write(serverFrame(true, opBinary, []byte("ls\n")))
+
+			_, payload := readClientFrame(r)
+			output <- payload
+		}
+
+		res := p.control(controlRequest{NodeID: "handle;<container>", Control: ExecShell})
+		Expect(res.Error).To(BeEmpty())
+		Expect(res.Pipe).ToNot(BeEmpty())
+		Expect(res.RawTTY).To(BeTrue())
+		Expect(res.ResizeTTYControl).To(Equal(ResizeExecTTY))
+
+		header := <-scope.header
+		Expect(header.Get("Authorization")).To(Equal("Scope-Probe token=secret"))
+
+		var spec garden.ProcessSpec
+		Eventually(container.spec).Should(Receive(&spec))
+		Expect(spec.Path).To(Equal("/bin/sh"))
+		Expect(spec.Args).To(Equal([]string{"-c", execShellCommand}))
+		Expect(spec.User).To(Equal("root"))
+		Expect(spec.TTY).ToNot(BeNil())
+
+		Eventually(output).Should(Receive(Equal([]byte("$ ls"))))
+
+		close(container.process.exited)
+	})
+
+	It("resizes the shell's TTY", func() {
+		scope.serve = func(conn net.Conn, r *bufio.Reader) {
+			r.ReadByte()
+		}
+
+		res := p.control(controlRequest{NodeID: "handle;<container>", Control: ExecShell})
+		Expect(res.Error).To(BeEmpty())
+
+		res = p.control(controlRequest{
+			NodeID:      "handle;<container>",
+			Control:     ResizeExecTTY,
+			ControlArgs: map[string]string{"pipeID": res.Pipe, "width": "80", "height": "24"},
+		})
+		Expect(res.Error).To(BeEmpty())
+
+		Expect(container.process.ttySpecs()).To(Equal([]garden.TTYSpec{{
+			WindowSize: &garden.WindowSize{Columns: 80, Rows: 24},
+		}}))
+
+		res = p.control(controlRequest{
+			NodeID:      "handle;<container>",
+			Control:     ResizeExecTTY,
+			ControlArgs: map[string]string{"pipeID": res.Pipe, "width": "wide", "height": "24"},
+		})
+		Expect(res.Error).To(Equal(`invalid tty width "wide"`))
+
+		close(container.process.exited)
+	})
+
+	It("forgets pipes once the shell exits", func() {
+		scope.serve = func(conn net.Conn, r *bufio.Reader) {
+			r.ReadByte()
+		}
+
+		id, err := p.pipes.exec(container)
+		Expect(err).ToNot(HaveOccurred())
+
+		close(container.process.exited)
+
+		Eventually(func() error {
+			return p.pipes.resize(id, 80, 24)
+		}).Should(MatchError(`unknown pipe "` + id + `"`))
+	})
+
+	It("connects to the pipe of the Scope app", func() {
+		Expect(newPipes("https://scope:4040/prefix", "").pipeURL("id")).To(Equal("wss://scope:4040/prefix/api/pipe/id/probe"))
+		Expect(newPipes("http://scope:4040", "").pipeURL("id")).To(Equal("ws://scope:4040/api/pipe/id/probe"))
+	})
+})
@@ -6,69 +6,242 @@ import (
 	"net/http"
 	"sync"
 	"time"
-
-	gardenclient "code.cloudfoundry.org/garden/client"
-	gardenconnection "code.cloudfoundry.org/garden/client/connection"
-	"github.com/concourse/atc"
 )
 
-type lookupFn func(string) (atc.Container, bool)
+// Config configures the plugin's connections and report collection.
+type Config struct {
+	Hostname      string
+	GardenNetwork string
+	GardenAddr    string
+	ScopeAppURL   string
+	ScopeToken    string
+
+	RefreshInterval  time.Duration
+	RefreshTimeout   time.Duration
+	ContainerTimeout time.Duration
+	Workers          int
+	CPUCores         int
+
+	// DepotDir and CgroupRoot are used to map Garden processes to host
+	// processes. Processes are reported without host details if either is
+	// empty.
+	DepotDir   string
+	CgroupRoot string
+
+	Properties PropertyRules
+}
 
 type plugin struct {
-	lock     sync.RWMutex
-	hostname string
-	registry *registry
-	done     chan struct{}
-	report   report
+	lock       sync.RWMutex
+	config     Config
+	hostname   string
+	enricher   chain
+	properties propertyFilter
+	processes  processSource
+	registry   *registry
+	pipes      *pipes
+	history    *history
+	counters   *counters
+	events     *eventLog
+	done       chan struct{}
+	stopped    chan struct{}
+	report     report
+
+	refreshInterval time.Duration
+	atcStatus       statusFn
+	cfStatus        statusFn
+	health          health
 }
 
-func NewPlugin(hostname, gardenNetwork, gardenAddr string, fetchInterval time.Duration, appNameLookup lookupFn) *plugin {
-	client := gardenclient.New(
-		gardenconnection.New(gardenNetwork, gardenAddr),
-	)
+// NewPlugin returns a plugin reporting the containers of the Garden server
+// in config, enriched by the given enrichers in order.
+func NewPlugin(config Config, enrichers ...Enricher) (*plugin, error) {
+	properties, err := config.Properties.compile()
+	if err != nil {
+		return nil, err
+	}
 
 	p := &plugin{
-		hostname: hostname,
-		registry: newRegistry(client),
-		report:   newReport(hostname, appNameLookup),
-		done:     make(chan struct{}),
+		pipes:    newPipes(config.ScopeAppURL, config.ScopeToken),
+		history:  newHistory(metricHistoryWindow, config.RefreshInterval),
+		counters: newCounters(config.CPUCores),
+		events:   newEventLog(),
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.configure(config, enrichers, properties)
+	p.report = newReport(p.hostname, p.enricher, p.properties, p.processes, p.counters, p.events)
+	p.refreshReport()
+
+	return p, nil
+}
+
+// Reconfigure applies a new configuration and enrichers to a running plugin.
+// The report, health, metric history, rate counters, events, open pipes and,
+// unless the Garden server changes, cached container data are kept, so
+// that Scope sees no gap while the plugin is reconfigured.
+func (p *plugin) Reconfigure(config Config, enrichers ...Enricher) error {
+	properties, err := config.Properties.compile()
+	if err != nil {
+		return err
+	}
+
+	p.stop()
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.configure(config, enrichers, properties)
+	p.refreshReport()
+
+	return nil
+}
+
+// configure sets everything that depends on the configuration. It must be
+// called with p.lock held while no refresh is running.
+func (p *plugin) configure(config Config, enrichers []Enricher, properties propertyFilter) {
+	registry := newRegistry(
+		newRefreshClient(config.GardenNetwork, config.GardenAddr, config.RefreshTimeout),
+		newControlClient(config.GardenNetwork, config.GardenAddr),
+		config.Workers,
+		config.RefreshTimeout,
+		config.ContainerTimeout,
+	)
+
+	if p.registry != nil && p.config.GardenNetwork == config.GardenNetwork && p.config.GardenAddr == config.GardenAddr {
+		registry.carry(p.registry)
+	}
+
+	p.config = config
+	p.hostname = config.Hostname
+	p.enricher = chain(enrichers)
+	p.properties = properties
+	p.processes = processSource{depotDir: config.DepotDir, cgroupRoot: config.CgroupRoot}
+	p.registry = registry
+	p.refreshInterval = config.RefreshInterval
+	p.atcStatus = concourseStatus(enrichers)
+	p.cfStatus = cfStatus(enrichers)
+
+	p.pipes.configure(config.ScopeAppURL, config.ScopeToken)
+	p.history.resize(metricHistoryWindow, config.RefreshInterval)
+	p.counters.setCores(config.CPUCores)
+}
+
+// concourseStatus returns the status of the ATC used by the Concourse
+// enricher, if there is one.
+func concourseStatus(enrichers []Enricher) statusFn {
+	for _, e := range enrichers {
+		if c, ok := e.(*concourseEnricher); ok {
+			return c.directory.Err
+		}
 	}
 
-	p.refreshReport(fetchInterval, appNameLookup)
+	return nil
+}
+
+// cfStatus returns the status of the Cloud Controller used by the CF
+// enricher, if there is one.
+func cfStatus(enrichers []Enricher) statusFn {
+	for _, e := range enrichers {
+		if c, ok := e.(*cfEnricher); ok {
+			if c.names == nil {
+				return func() error { return nil }
+			}
+			return c.names.Err
+		}
+	}
 
-	return p
+	return nil
 }
 
 func (p *plugin) Close() {
-	close(p.done)
+	p.stop()
 }
 
-func (p *plugin) refreshReport(interval time.Duration, appNameLookup lookupFn) {
+// stop ends the refresh loop and waits for a running refresh to finish.
+func (p *plugin) stop() {
+	p.lock.RLock()
+	done, stopped := p.done, p.stopped
+	p.lock.RUnlock()
+
+	close(done)
+	<-stopped
+}
+
+// refreshReport starts the refresh loop. It must be called with p.lock held.
+// The loop reads the configuration without the lock, which is safe because
+// it is only changed while the loop is stopped.
+func (p *plugin) refreshReport() {
+	done, stopped := make(chan struct{}), make(chan struct{})
+	p.done, p.stopped = done, stopped
+
+	interval := p.refreshInterval
+
 	go func() {
+		defer close(stopped)
+
 		for {
 			select {
 			case <-time.After(interval):
-				r := newReport(p.hostname, appNameLookup)
-
-				if err := p.registry.walkContainers(r.AddNode); err != nil {
-					log.Println(err)
-				}
-
-				p.lock.Lock()
-				p.report = r
-				p.lock.Unlock()
-			case <-p.done:
+				p.refresh()
+			case <-done:
 				return
 			}
 		}
 	}()
 }
 
+func (p *plugin) refresh() {
+	r := newReport(p.hostname, p.enricher, p.properties, p.processes, p.counters, p.events)
+
+	errs, err := p.registry.walkContainers(r.AddNode)
+	if err != nil {
+		log.Println(err)
+	}
+	for _, err := range errs {
+		log.Println(err)
+	}
+	p.setHealth(err, errs, time.Now())
+	if err != nil {
+		// Keep serving the last report, which is marked as stale once it
+		// gets too old.
+		return
+	}
+
+	now := time.Now()
+	destroyed := p.events.destroyed(now)
+
+	p.counters.rotate()
+	p.events.rotate(now)
+
+	if capacity, err := p.registry.capacity(); err != nil {
+		log.Println(err)
+		r.AddHost(nil)
+	} else {
+		r.AddHost(&capacity)
+	}
+
+	p.enricher.addTopologies(&r)
+
+	for _, n := range destroyed {
+		r.addDestroyed(n)
+	}
+
+	p.history.record(r.Container.Nodes, r.Process.Nodes, r.Host.Nodes)
+
+	p.lock.Lock()
+	p.report = r
+	p.lock.Unlock()
+}
+
 func (p *plugin) Report(w http.ResponseWriter, r *http.Request) {
 	p.lock.RLock()
-	defer p.lock.RUnlock()
+	rpt := p.report.withStatus(p.status().Status)
+	p.lock.RUnlock()
 
-	if err := json.NewEncoder(w).Encode(p.report); err != nil {
+	if err := json.NewEncoder(w).Encode(rpt); err != nil {
 		log.Printf("error encoding report: %v\n", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 	}
@@ -0,0 +1,85 @@
+package garden
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("plugin", func() {
+	var (
+		config Config
+		p      *plugin
+	)
+
+	BeforeEach(func() {
+		config = Config{
+			Hostname:         "host",
+			GardenNetwork:    "unix",
+			GardenAddr:       "/nonexistent/garden.sock",
+			RefreshInterval:  time.Hour,
+			RefreshTimeout:   time.Second,
+			ContainerTimeout: time.Second,
+			Workers:          1,
+			CPUCores:         1,
+		}
+
+		var err error
+		p, err = NewPlugin(config)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		p.Close()
+	})
+
+	It("applies every setting on reconfiguration", func() {
+		config.Hostname = "other"
+		config.GardenAddr = "/nonexistent/other.sock"
+		config.RefreshInterval = 30 * time.Minute
+		config.Workers = 4
+		config.CPUCores = 2
+		config.ScopeAppURL = "http://scope:4040"
+		config.DepotDir = "/depot"
+		config.Properties.Deny = []string{"secret"}
+
+		Expect(p.Reconfigure(config)).To(Succeed())
+
+		Expect(p.config).To(Equal(config))
+		Expect(p.hostname).To(Equal("other"))
+		Expect(p.refreshInterval).To(Equal(30 * time.Minute))
+		Expect(p.registry.workers).To(Equal(4))
+		Expect(p.counters.cores).To(Equal(2))
+		Expect(p.history.size).To(Equal(historySize(metricHistoryWindow, 30*time.Minute)))
+		Expect(p.pipes.appURL).To(Equal("http://scope:4040"))
+		Expect(p.processes.depotDir).To(Equal("/depot"))
+		_, allowed := p.properties.allowed("secret", "value")
+		Expect(allowed).To(BeFalse())
+	})
+
+	It("keeps what was collected across reconfiguration", func() {
+		p.setHealth(nil, nil, time.Now())
+		health := p.health
+		rpt, history, counters, events := p.report, p.history, p.counters, p.events
+
+		p.registry.limits["handle"] = limits{}
+		config.Workers = 2
+		Expect(p.Reconfigure(config)).To(Succeed())
+
+		Expect(p.report).To(Equal(rpt))
+		Expect(p.health).To(Equal(health))
+		Expect(p.history).To(BeIdenticalTo(history))
+		Expect(p.counters).To(BeIdenticalTo(counters))
+		Expect(p.events).To(BeIdenticalTo(events))
+		Expect(p.registry.limits).To(HaveKey("handle"))
+	})
+
+	It("keeps the current configuration if the property rules are invalid", func() {
+		config.Workers = 2
+		config.Properties.Allow = []string{"[a-"}
+
+		Expect(p.Reconfigure(config)).ToNot(Succeed())
+		Expect(p.registry.workers).To(Equal(1))
+	})
+})
@@ -0,0 +1,201 @@
+package garden
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	// clockTicks is the value of USER_HZ, which is 100 on all platforms
+	// Garden runs on.
+	clockTicks = 100
+)
+
+// ProcessTopology is Scope's process topology, to which the processes Garden
+// runs in containers are added. Processes mapped to a host PID share their
+// node with the one Scope's probe reports for that PID.
+const ProcessTopology = "process"
+
+var procRoot = "/proc"
+
+// processSource maps Garden processes to host processes. Guardian writes the
+// host PID of every process it runs to a pidfile in its depot, and the PID
+// is only trusted if it is also a member of the container's cgroup.
+type processSource struct {
+	depotDir   string
+	cgroupRoot string
+}
+
+type procInfo struct {
+	cmdline  string
+	name     string
+	rssBytes uint64
+	cpuTime  float64
+}
+
+// cgroupProcs returns the host PIDs in a container's cgroup. It returns nil
+// if the cgroup cannot be read, in which case no process can be mapped.
+func (s processSource) cgroupProcs(handle string) map[int]bool {
+	if s.depotDir == "" || s.cgroupRoot == "" {
+		return nil
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(s.cgroupRoot, handle, "cgroup.procs"))
+	if err != nil {
+		return nil
+	}
+
+	procs := map[int]bool{}
+	for _, line := range strings.Fields(string(data)) {
+		if pid, err := strconv.Atoi(line); err == nil {
+			procs[pid] = true
+		}
+	}
+
+	return procs
+}
+
+// hostPID returns the host PID of a Garden process if it is one of procs.
+func (s processSource) hostPID(handle, id string, procs map[int]bool) (int, bool) {
+	if len(procs) == 0 || strings.ContainsAny(id, `/\`) {
+		return 0, false
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(s.depotDir, handle, "processes", id, "pidfile"))
+	if err != nil {
+		return 0, false
+	}
+
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || !procs[pid] {
+		return 0, false
+	}
+
+	return pid, true
+}
+
+// readProc reads a host process from /proc.
+func readProc(pid int) (procInfo, bool) {
+	dir := filepath.Join(procRoot, strconv.Itoa(pid))
+
+	cmdline, err := ioutil.ReadFile(filepath.Join(dir, "cmdline"))
+	if err != nil {
+		return procInfo{}, false
+	}
+
+	info := procInfo{
+		cmdline: strings.TrimSpace(strings.Replace(string(cmdline), "\x00", " ", -1)),
+	}
+
+	if fields := strings.Fields(info.cmdline); len(fields) > 0 {
+		info.name = filepath.Base(fields[0])
+	}
+
+	if statm, err := ioutil.ReadFile(filepath.Join(dir, "statm")); err == nil {
+		if fields := strings.Fields(string(statm)); len(fields) > 1 {
+			pages, _ := strconv.ParseUint(fields[1], 10, 64)
+			info.rssBytes = pages * uint64(os.Getpagesize())
+		}
+	}
+
+	if stat, err := ioutil.ReadFile(filepath.Join(dir, "stat")); err == nil {
+		info.cpuTime = cpuTime(string(stat))
+	}
+
+	return info, true
+}
+
+// cpuTime returns user plus system time in seconds from the contents of
+// /proc/<pid>/stat. The command name may contain spaces, so fields are
+// counted from its closing parenthesis.
+func cpuTime(stat string) float64 {
+	i := strings.LastIndex(stat, ")")
+	if i < 0 {
+		return 0
+	}
+
+	// utime and stime are fields 14 and 15, i.e. 12 and 13 after the command.
+	fields := strings.Fields(stat[i+1:])
+	if len(fields) < 13 {
+		return 0
+	}
+
+	utime, _ := strconv.ParseUint(fields[11], 10, 64)
+	stime, _ := strconv.ParseUint(fields[12], 10, 64)
+
+	return float64(utime+stime) / clockTicks
+}
+
+func (r *report) addProcessNodes(handle, containerNodeID, host string, processIDs []string) {
+	var procs map[int]bool
+	if len(processIDs) > 0 {
+		procs = r.processes.cgroupProcs(handle)
+	}
+
+	for _, id := range processIDs {
+		pid, mapped := r.processes.hostPID(handle, id, procs)
+
+		n := nodeSpec{
+			ID:       processNodeID(r.hostname, handle, id, pid, mapped),
+			Topology: ProcessTopology,
+			Parents: map[string][]string{
+				"host":      {host},
+				"container": {containerNodeID},
+			},
+			Latest: map[string]latestSpec{
+				ProcessID:        latest(id),
+				ProcessContainer: latest(handle),
+				"name":           latest(id),
+				"host_node_id":   latest(host),
+			},
+		}
+
+		if !mapped {
+			r.Process.Nodes[n.ID] = n
+			continue
+		}
+
+		n.Latest[ProcessHostPID] = latest(strconv.Itoa(pid))
+		n.Latest["pid"] = latest(strconv.Itoa(pid))
+
+		if info, found := readProc(pid); found {
+			n.Latest[ProcessCmdline] = latest(info.cmdline)
+			if info.name != "" {
+				n.Latest["name"] = latest(info.name)
+			}
+
+			n.Metrics = map[string]metricSpec{
+				ProcessMemoryUsage: metric(float64(info.rssBytes)),
+				ProcessCPUTime:     metric(info.cpuTime),
+			}
+		}
+
+		r.Process.Nodes[n.ID] = n
+	}
+}
+
+// processNodeID returns the ID Scope uses for the process with the given
+// host PID, i.e. hostname;pid. Processes without a host PID are namespaced
+// with their container's handle instead.
+func processNodeID(hostname, handle, id string, pid int, mapped bool) string {
+	if !mapped {
+		return fmt.Sprintf("%s;%s/%s", hostname, handle, id)
+	}
+
+	return fmt.Sprintf("%s;%d", hostname, pid)
+}
+
+func newProcess() topologySpec {
+	return topologySpec{
+		Label:             "process",
+		LabelPlural:       "processes",
+		Shape:             "square",
+		MetadataTemplates: processMetadataTemplates,
+		MetricTemplates:   processMetricTemplates,
+		Nodes:             map[string]nodeSpec{},
+	}
+}
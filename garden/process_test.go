@@ -0,0 +1,140 @@
+package garden
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("processes", func() {
+	var (
+		root         string
+		originalProc string
+		r            report
+	)
+
+	write := func(content string, elem ...string) {
+		path := filepath.Join(append([]string{root}, elem...)...)
+		Expect(os.MkdirAll(filepath.Dir(path), 0755)).To(Succeed())
+		Expect(ioutil.WriteFile(path, []byte(content), 0644)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		var err error
+		root, err = ioutil.TempDir("", "garden-process")
+		Expect(err).ToNot(HaveOccurred())
+
+		originalProc = procRoot
+		procRoot = filepath.Join(root, "proc")
+
+		processes := processSource{
+			depotDir:   filepath.Join(root, "depot"),
+			cgroupRoot: filepath.Join(root, "cgroup"),
+		}
+		r = newReport("host", nil, propertyFilter{}, processes, newCounters(1), newEventLog())
+
+		write("4242\n4243\n", "cgroup", "handle", "cgroup.procs")
+		write("4242\n", "depot", "handle", "processes", "sh", "pidfile")
+		write("/bin/sh\x00-c\x00sleep 1\x00", "proc", "4242", "cmdline")
+		write("0 25", "proc", "4242", "statm")
+		write("4242 (s h) S 1 1 1 0 -1 0 0 0 0 0 150 50 0 0", "proc", "4242", "stat")
+		write("/sbin/init\x00", "proc", "1", "cmdline")
+	})
+
+	AfterEach(func() {
+		procRoot = originalProc
+		os.RemoveAll(root)
+	})
+
+	It("adds processes mapped to a host PID to Scope's node for that PID", func() {
+		r.addProcessNodes("handle", "handle;<container>", "host;<host>", []string{"sh"})
+
+		Expect(r.Process.Nodes).To(HaveKey("host;4242"))
+		n := r.Process.Nodes["host;4242"]
+		Expect(n.Topology).To(Equal("process"))
+		Expect(n.Parents["container"]).To(Equal([]string{"handle;<container>"}))
+		Expect(n.Parents["host"]).To(Equal([]string{"host;<host>"}))
+	})
+
+	It("enriches processes mapped to a host PID in the container's cgroup", func() {
+		r.addProcessNodes("handle", "handle;<container>", "host;<host>", []string{"sh"})
+
+		n := r.Process.Nodes["host;4242"]
+		Expect(n.Latest[ProcessID].Value).To(Equal("sh"))
+		Expect(n.Latest[ProcessHostPID].Value).To(Equal("4242"))
+		Expect(n.Latest["pid"].Value).To(Equal("4242"))
+		Expect(n.Latest[ProcessCmdline].Value).To(Equal("/bin/sh -c sleep 1"))
+		Expect(n.Latest["name"].Value).To(Equal("sh"))
+		Expect(n.Metrics[ProcessCPUTime].Samples[0].Value).To(Equal(2.0))
+		Expect(n.Metrics[ProcessMemoryUsage].Samples[0].Value).To(Equal(float64(25 * os.Getpagesize())))
+	})
+
+	It("namespaces processes without a host PID with the container handle", func() {
+		r.processes.cgroupRoot = ""
+		r.addProcessNodes("handle", "handle;<container>", "host;<host>", []string{"sh"})
+
+		Expect(r.Process.Nodes).To(HaveKey("host;handle/sh"))
+		n := r.Process.Nodes["host;handle/sh"]
+		Expect(n.Parents["container"]).To(Equal([]string{"handle;<container>"}))
+		Expect(n.Latest).ToNot(HaveKey(ProcessHostPID))
+		Expect(n.Latest).ToNot(HaveKey("pid"))
+	})
+
+	It("does not read numeric process IDs from the host's /proc", func() {
+		r.addProcessNodes("handle", "handle;<container>", "host;<host>", []string{"1"})
+
+		Expect(r.Process.Nodes).ToNot(HaveKey("host;1"))
+		n := r.Process.Nodes["host;handle/1"]
+		Expect(n.Latest).ToNot(HaveKey(ProcessHostPID))
+		Expect(n.Latest).ToNot(HaveKey(ProcessCmdline))
+		Expect(n.Metrics).To(BeEmpty())
+	})
+
+	It("does not trust pidfiles pointing outside the container's cgroup", func() {
+		write("1\n", "depot", "handle", "processes", "sh", "pidfile")
+		r.addProcessNodes("handle", "handle;<container>", "host;<host>", []string{"sh"})
+
+		Expect(r.Process.Nodes).To(HaveLen(1))
+		n := r.Process.Nodes["host;handle/sh"]
+		Expect(n.Latest).ToNot(HaveKey(ProcessHostPID))
+		Expect(n.Latest).ToNot(HaveKey(ProcessCmdline))
+	})
+
+	It("reports processes in the topology Scope decodes", func() {
+		r.addProcessNodes("handle", "handle;<container>", "host;<host>", []string{"sh"})
+
+		data, err := json.Marshal(r)
+		Expect(err).ToNot(HaveOccurred())
+
+		var topologies map[string]json.RawMessage
+		Expect(json.Unmarshal(data, &topologies)).To(Succeed())
+		Expect(topologies).ToNot(HaveKey("GardenProcess"))
+
+		var decoded struct {
+			Process struct {
+				Nodes map[string]struct {
+					Topology string
+					Parents  map[string][]string
+				}
+			}
+		}
+		Expect(json.Unmarshal(data, &decoded)).To(Succeed())
+		Expect(decoded.Process.Nodes).To(HaveKey("host;4242"))
+		Expect(decoded.Process.Nodes["host;4242"].Topology).To(Equal("process"))
+		Expect(decoded.Process.Nodes["host;4242"].Parents["container"]).To(Equal([]string{"handle;<container>"}))
+	})
+
+	It("rejects process IDs escaping the depot", func() {
+		_, found := r.processes.hostPID("handle", "../../handle/processes/sh", map[int]bool{4242: true})
+		Expect(found).To(BeFalse())
+	})
+
+	It("reads CPU time from stat after the command name", func() {
+		Expect(cpuTime("1 (a) b) S 0 0 0 0 0 0 0 0 0 0 300 100 0 0")).To(Equal(4.0))
+		Expect(cpuTime("garbage")).To(BeZero())
+	})
+})
@@ -0,0 +1,225 @@
+package garden
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"code.cloudfoundry.org/garden"
+)
+
+const redacted = "[REDACTED]"
+
+// PropertyRules decide which Garden properties are reported and how.
+//
+// Allow and Deny match property keys. A pattern enclosed in slashes, such as
+// /^network\./, is a regular expression, anything else is a glob. If Allow is
+// not empty, only keys matching one of its patterns are reported. Keys
+// matching Deny are never reported. Matches of the regular expressions in
+// Redact are replaced in property values. Values longer than MaxValueLength
+// are truncated by Scope, unless it is 0.
+//
+// Enrichers see all properties, but the rules also apply to what they add to
+// the report: values of properties that are not reported are left out and
+// Redact applies to all values.
+type PropertyRules struct {
+	Allow          []string
+	Deny           []string
+	Redact         []string
+	MaxValueLength int
+}
+
+type propertyFilter struct {
+	allow          []matcher
+	deny           []matcher
+	redact         []*regexp.Regexp
+	maxValueLength int
+}
+
+type matcher func(string) bool
+
+// Validate checks that all patterns compile.
+func (r PropertyRules) Validate() error {
+	_, err := r.compile()
+	return err
+}
+
+func (r PropertyRules) compile() (propertyFilter, error) {
+	var (
+		f   = propertyFilter{maxValueLength: r.MaxValueLength}
+		err error
+	)
+
+	if r.MaxValueLength < 0 {
+		return f, fmt.Errorf("invalid max property value length %d", r.MaxValueLength)
+	}
+
+	if f.allow, err = matchers(r.Allow); err != nil {
+		return f, err
+	}
+
+	if f.deny, err = matchers(r.Deny); err != nil {
+		return f, err
+	}
+
+	for _, p := range r.Redact {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return f, fmt.Errorf("invalid property redaction pattern %q: %v", p, err)
+		}
+		f.redact = append(f.redact, re)
+	}
+
+	return f, nil
+}
+
+func matchers(patterns []string) ([]matcher, error) {
+	var ms []matcher
+
+	for _, p := range patterns {
+		if len(p) > 1 && strings.HasPrefix(p, "/") && strings.HasSuffix(p, "/") {
+			re, err := regexp.Compile(p[1 : len(p)-1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid property pattern %q: %v", p, err)
+			}
+			ms = append(ms, re.MatchString)
+			continue
+		}
+
+		if _, err := path.Match(p, ""); err != nil {
+			return nil, fmt.Errorf("invalid property pattern %q: %v", p, err)
+		}
+
+		glob := p
+		ms = append(ms, func(key string) bool {
+			matched, _ := path.Match(glob, key)
+			return matched
+		})
+	}
+
+	return ms, nil
+}
+
+// filterAll returns the properties that may be reported, with redacted
+// values.
+func (f propertyFilter) filterAll(properties garden.Properties) garden.Properties {
+	filtered := garden.Properties{}
+
+	for k, v := range properties {
+		if v, ok := f.allowed(k, v); ok {
+			filtered[k] = v
+		}
+	}
+
+	return filtered
+}
+
+// allowed returns the redacted value of a property and false if the property
+// must not be reported.
+func (f propertyFilter) allowed(key, value string) (string, bool) {
+	if len(f.allow) > 0 && !matchAny(f.allow, key) {
+		return "", false
+	}
+
+	if matchAny(f.deny, key) {
+		return "", false
+	}
+
+	return f.redactValue(value), true
+}
+
+func (f propertyFilter) redactValue(value string) string {
+	for _, re := range f.redact {
+		value = re.ReplaceAllLiteralString(value, redacted)
+	}
+
+	return value
+}
+
+// scrub applies the rules to an enrichment of a container with the given
+// properties. Values that equal the value of a property that must not be
+// reported are dropped, and all other values are redacted.
+func (f propertyFilter) scrub(e Enrichment, properties garden.Properties) Enrichment {
+	hidden := map[string]bool{}
+	for k, v := range properties {
+		if _, ok := f.allowed(k, v); !ok && v != "" {
+			hidden[v] = true
+		}
+	}
+
+	clean := func(v string) (string, bool) {
+		if hidden[v] {
+			return "", false
+		}
+		return f.redactValue(v), true
+	}
+
+	scrubbed := Enrichment{}
+	scrubbed.Name, _ = clean(e.Name)
+	scrubbed.Image, _ = clean(e.Image)
+
+	for _, field := range e.Latest {
+		if v, ok := clean(field.Value); ok {
+			field.Value = v
+			scrubbed.Latest = append(scrubbed.Latest, field)
+		}
+	}
+
+	for _, t := range e.Tables {
+		rows := map[string]string{}
+		for k, v := range t.Rows {
+			if v, ok := clean(v); ok {
+				rows[k] = v
+			}
+		}
+		scrubbed.Tables = append(scrubbed.Tables, Table{ID: t.ID, Label: t.Label, Rows: rows})
+	}
+
+	for k, v := range e.Labels {
+		if v, ok := clean(v); ok {
+			if scrubbed.Labels == nil {
+				scrubbed.Labels = map[string]string{}
+			}
+			scrubbed.Labels[k] = v
+		}
+	}
+
+	return scrubbed
+}
+
+// addProperties adds the properties to a container node. Every property has
+// its own metadata template, which lets Scope truncate long values.
+func (r *report) addProperties(n *nodeSpec, properties garden.Properties) {
+	keys := make([]string, 0, len(properties))
+	for k := range properties {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		id := fmt.Sprintf("%s%s", ContainerPropertiesPrefix, k)
+		n.Latest[id] = latest(properties[k])
+
+		if _, found := r.Container.MetadataTemplates[id]; !found {
+			r.Container.MetadataTemplates[id] = metadataTemplateSpec{
+				ID:       id,
+				Label:    k,
+				From:     "latest",
+				Priority: len(r.Container.MetadataTemplates) + 1,
+				Truncate: r.properties.maxValueLength,
+			}
+		}
+	}
+}
+
+func matchAny(ms []matcher, key string) bool {
+	for _, m := range ms {
+		if m(key) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,97 @@
+package garden
+
+import (
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/garden"
+)
+
+// counters remembers the cumulative CPU and network counters of every
+// container from the previous refresh, so that per-interval rates can be
+// derived from the next one.
+type counters struct {
+	lock    sync.Mutex
+	cores   int
+	last    map[string]counterSample
+	current map[string]counterSample
+}
+
+type counterSample struct {
+	at  time.Time
+	cpu uint64
+	rx  uint64
+	tx  uint64
+}
+
+type rates struct {
+	cpuPercent float64
+	rxPerSec   float64
+	txPerSec   float64
+}
+
+// newCounters returns counters that report CPU usage relative to a single
+// core, or relative to all cores if cores is greater than one.
+func newCounters(cores int) *counters {
+	c := &counters{
+		last:    map[string]counterSample{},
+		current: map[string]counterSample{},
+	}
+	c.setCores(cores)
+
+	return c
+}
+
+// setCores sets the number of cores CPU usage is relative to.
+func (c *counters) setCores(cores int) {
+	if cores < 1 {
+		cores = 1
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.cores = cores
+}
+
+// observe records the counters of a container and returns the rates since
+// the previous refresh. It returns false if there is no previous sample or
+// the counters have been reset in the meantime.
+func (c *counters) observe(handle string, m garden.Metrics, at time.Time) (rates, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	sample := counterSample{
+		at:  at,
+		cpu: m.CPUStat.Usage,
+		rx:  m.NetworkStat.RxBytes,
+		tx:  m.NetworkStat.TxBytes,
+	}
+	c.current[handle] = sample
+
+	prev, found := c.last[handle]
+	if !found {
+		return rates{}, false
+	}
+
+	elapsed := sample.at.Sub(prev.at)
+	if elapsed <= 0 || sample.cpu < prev.cpu || sample.rx < prev.rx || sample.tx < prev.tx {
+		return rates{}, false
+	}
+
+	return rates{
+		cpuPercent: float64(sample.cpu-prev.cpu) / float64(elapsed) * 100 / float64(c.cores),
+		rxPerSec:   float64(sample.rx-prev.rx) / elapsed.Seconds(),
+		txPerSec:   float64(sample.tx-prev.tx) / elapsed.Seconds(),
+	}, true
+}
+
+// rotate makes the samples observed during the current refresh the basis for
+// the next one and forgets containers that were not observed.
+func (c *counters) rotate() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.last = c.current
+	c.current = map[string]counterSample{}
+}
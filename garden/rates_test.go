@@ -0,0 +1,70 @@
+package garden
+
+import (
+	"time"
+
+	"code.cloudfoundry.org/garden"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("counters", func() {
+	var (
+		c   *counters
+		now time.Time
+	)
+
+	metrics := func(cpu time.Duration, rx, tx uint64) garden.Metrics {
+		return garden.Metrics{
+			CPUStat:     garden.ContainerCPUStat{Usage: uint64(cpu)},
+			NetworkStat: garden.ContainerNetworkStat{RxBytes: rx, TxBytes: tx},
+		}
+	}
+
+	BeforeEach(func() {
+		c = newCounters(1)
+		now = time.Now()
+	})
+
+	It("has no rates for the first sample", func() {
+		_, ok := c.observe("handle", metrics(time.Second, 100, 100), now)
+		Expect(ok).To(BeFalse())
+	})
+
+	It("computes rates between refreshes", func() {
+		c.observe("handle", metrics(time.Second, 100, 200), now)
+		c.rotate()
+
+		r, ok := c.observe("handle", metrics(2*time.Second, 300, 1200), now.Add(2*time.Second))
+		Expect(ok).To(BeTrue())
+		Expect(r.cpuPercent).To(BeNumerically("~", 50))
+		Expect(r.rxPerSec).To(BeNumerically("~", 100))
+		Expect(r.txPerSec).To(BeNumerically("~", 500))
+	})
+
+	It("normalises CPU usage to the number of cores", func() {
+		c = newCounters(4)
+		c.observe("handle", metrics(0, 0, 0), now)
+		c.rotate()
+
+		r, _ := c.observe("handle", metrics(2*time.Second, 0, 0), now.Add(time.Second))
+		Expect(r.cpuPercent).To(BeNumerically("~", 50))
+	})
+
+	It("ignores counters that have been reset", func() {
+		c.observe("handle", metrics(time.Second, 100, 100), now)
+		c.rotate()
+
+		_, ok := c.observe("handle", metrics(0, 0, 0), now.Add(time.Second))
+		Expect(ok).To(BeFalse())
+	})
+
+	It("forgets containers that were not observed", func() {
+		c.observe("handle", metrics(time.Second, 100, 100), now)
+		c.rotate()
+		c.rotate()
+
+		_, ok := c.observe("handle", metrics(2*time.Second, 200, 200), now.Add(time.Second))
+		Expect(ok).To(BeFalse())
+	})
+})
@@ -1,34 +1,370 @@
 package garden
 
 import (
+	"errors"
 	"fmt"
+	"log"
+	"sync"
+	"time"
 
 	"code.cloudfoundry.org/garden"
 	gardenclient "code.cloudfoundry.org/garden/client"
 )
 
 type registry struct {
-	client gardenclient.Client
+	// client makes the calls of a refresh and control looks up containers
+	// for controls and pipes, whose calls must not be cut short.
+	client  gardenclient.Client
+	control gardenclient.Client
+
+	workers          int
+	refreshTimeout   time.Duration
+	containerTimeout time.Duration
+
+	lock   sync.Mutex
+	limits map[string]limits
+	last   map[string]containerEntry
+}
+
+type containerEntry struct {
+	container garden.Container
+	info      garden.ContainerInfo
+	metrics   garden.Metrics
+	limits    limits
+	stale     bool
 }
 
-func newRegistry(client gardenclient.Client) *registry {
+var errTimeout = errors.New("timed out")
+
+func newRegistry(client, control gardenclient.Client, workers int, refreshTimeout, containerTimeout time.Duration) *registry {
+	if workers < 1 {
+		workers = 1
+	}
+
 	return &registry{
-		client: client,
+		client:           client,
+		control:          control,
+		workers:          workers,
+		refreshTimeout:   refreshTimeout,
+		containerTimeout: containerTimeout,
+		limits:           map[string]limits{},
+		last:             map[string]containerEntry{},
 	}
 }
 
-func (r *registry) walkContainers(fn func(c garden.Container) error) error {
-	containers, err := r.client.Containers(garden.Properties{})
-	if err != nil {
-		err := fmt.Errorf("error fetching containers: %v\n", err)
+// walkContainers fetches info and metrics for all containers with one bulk
+// call each and calls fn for every container. Containers that cannot be
+// fetched or that fn fails on are skipped and their errors returned, so that
+// a single bad container does not affect the others. The returned error is
+// only set if no containers could be walked at all.
+//
+// If the bulk calls do not finish before the deadline of the whole refresh,
+// all containers are walked with their last known data and marked stale.
+// The only per-container call is the lookup of limits that are not cached
+// yet, which is made by a bounded pool of workers and marks the container
+// stale if it exceeds the container deadline.
+//
+// Calls that time out keep running until the deadline of their connection,
+// see newRefreshClient, so a hung Garden server does not accumulate calls.
+func (r *registry) walkContainers(fn func(e containerEntry) error) ([]error, error) {
+	deadline := time.Now().Add(r.refreshTimeout)
+
+	var containers []garden.Container
+	err := until(deadline, func() error {
+		var err error
+		containers, err = r.client.Containers(garden.Properties{})
 		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error fetching containers: %v", err)
+	}
+
+	handles := make([]string, len(containers))
+	for i, c := range containers {
+		handles[i] = c.Handle()
+	}
+
+	r.prune(handles)
+
+	infos, metrics, err := r.bulk(handles, deadline)
+	if err != nil && err != errTimeout {
+		return nil, err
+	}
+
+	bulkTimedOut := err == errTimeout
+
+	entries := make([]containerEntry, len(containers))
+	errs := make([]error, len(containers))
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+
+	for w := 0; w < r.workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if bulkTimedOut {
+					entries[i], errs[i] = r.staleEntry(handles[i], errTimeout)
+					continue
+				}
+
+				entries[i], errs[i] = r.entry(containers[i], infos, metrics, deadline)
+			}
+		}()
+	}
+
+	for i := range containers {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	var walkErrs []error
+
+	for i, e := range entries {
+		if errs[i] != nil {
+			walkErrs = append(walkErrs, errs[i])
+			continue
+		}
+
+		if err := fn(e); err != nil {
+			walkErrs = append(walkErrs, err)
+		}
 	}
 
-	for _, c := range containers {
-		if err := fn(c); err != nil {
+	return walkErrs, nil
+}
+
+// bulk fetches info and metrics for all handles concurrently. It returns
+// errTimeout if either call does not finish before the deadline.
+func (r *registry) bulk(handles []string, deadline time.Time) (map[string]garden.ContainerInfoEntry, map[string]garden.ContainerMetricsEntry, error) {
+	var (
+		infos      map[string]garden.ContainerInfoEntry
+		metrics    map[string]garden.ContainerMetricsEntry
+		infoErr    error
+		metricsErr error
+		wg         sync.WaitGroup
+	)
+
+	wg.Add(2)
+
+	go func() {
+		defer wg.Done()
+		infoErr = until(deadline, func() error {
+			i, err := r.client.BulkInfo(handles)
+			infos = i
+			return err
+		})
+	}()
+
+	go func() {
+		defer wg.Done()
+		metricsErr = until(deadline, func() error {
+			m, err := r.client.BulkMetrics(handles)
+			metrics = m
 			return err
+		})
+	}()
+
+	wg.Wait()
+
+	if infoErr == errTimeout || metricsErr == errTimeout {
+		return nil, nil, errTimeout
+	}
+
+	if infoErr != nil {
+		return nil, nil, fmt.Errorf("error fetching container info: %v", infoErr)
+	}
+
+	if metricsErr != nil {
+		return nil, nil, fmt.Errorf("error fetching container metrics: %v", metricsErr)
+	}
+
+	return infos, metrics, nil
+}
+
+func (r *registry) entry(c garden.Container, infos map[string]garden.ContainerInfoEntry, metrics map[string]garden.ContainerMetricsEntry, deadline time.Time) (containerEntry, error) {
+	id := c.Handle()
+
+	info, found := infos[id]
+	if !found {
+		return containerEntry{}, fmt.Errorf("no info returned for container %q", id)
+	}
+	if info.Err != nil {
+		return containerEntry{}, fmt.Errorf("error retrieving info for container %q: %v", id, info.Err)
+	}
+
+	m, found := metrics[id]
+	if !found {
+		return containerEntry{}, fmt.Errorf("no metrics returned for container %q", id)
+	}
+	if m.Err != nil {
+		return containerEntry{}, fmt.Errorf("error retrieving metrics for container %q: %v", id, m.Err)
+	}
+
+	if d := time.Now().Add(r.containerTimeout); d.Before(deadline) {
+		deadline = d
+	}
+
+	var l limits
+	if err := until(deadline, func() error {
+		l = r.containerLimits(c)
+		return nil
+	}); err != nil {
+		return r.staleEntry(id, err)
+	}
+
+	e := containerEntry{
+		container: c,
+		info:      info.Info,
+		metrics:   m.Metrics,
+		limits:    l,
+	}
+
+	r.lock.Lock()
+	r.last[id] = e
+	r.lock.Unlock()
+
+	return e, nil
+}
+
+// staleEntry returns the last known data of a container, marked as stale.
+func (r *registry) staleEntry(handle string, cause error) (containerEntry, error) {
+	r.lock.Lock()
+	e, found := r.last[handle]
+	r.lock.Unlock()
+
+	if !found {
+		return containerEntry{}, fmt.Errorf("error retrieving container %q: %v", handle, cause)
+	}
+
+	e.stale = true
+	return e, nil
+}
+
+// until calls fn and waits for it to return until the deadline has passed,
+// in which case errTimeout is returned. Garden calls cannot be cancelled, so
+// fn keeps running in the background after a timeout until the deadline of
+// its connection.
+func until(deadline time.Time, fn func() error) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- fn()
+	}()
+
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+
+	select {
+	case err := <-done:
+		return err
+	case <-timer.C:
+		return errTimeout
+	}
+}
+
+// containerLimits returns the limits of a container. Limits are not part of
+// the bulk calls and rarely change, so they are only fetched until they have
+// been fetched completely once for every container. Incomplete limits are
+// returned but not cached, so a transient error does not make a container
+// appear unlimited for the rest of its life.
+func (r *registry) containerLimits(c garden.Container) limits {
+	r.lock.Lock()
+	l, found := r.limits[c.Handle()]
+	r.lock.Unlock()
+
+	if found {
+		return l
+	}
+
+	l, err := containerLimits(c)
+	if err != nil {
+		log.Println(err)
+		return l
+	}
+
+	r.lock.Lock()
+	r.limits[c.Handle()] = l
+	r.lock.Unlock()
+
+	return l
+}
+
+// carry takes over the cached limits and last known data of the containers
+// of a previous registry for the same Garden server.
+func (r *registry) carry(previous *registry) {
+	previous.lock.Lock()
+	defer previous.lock.Unlock()
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	for h, l := range previous.limits {
+		r.limits[h] = l
+	}
+
+	for h, e := range previous.last {
+		r.last[h] = e
+	}
+}
+
+// prune forgets the limits and last known data of containers that no longer
+// exist.
+func (r *registry) prune(handles []string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	current := map[string]bool{}
+	for _, h := range handles {
+		current[h] = true
+	}
+
+	for h := range r.limits {
+		if !current[h] {
+			delete(r.limits, h)
 		}
 	}
 
+	for h := range r.last {
+		if !current[h] {
+			delete(r.last, h)
+		}
+	}
+}
+
+func (r *registry) capacity() (garden.Capacity, error) {
+	var capacity garden.Capacity
+	err := until(time.Now().Add(r.containerTimeout), func() error {
+		var err error
+		capacity, err = r.client.Capacity()
+		return err
+	})
+	if err != nil {
+		return garden.Capacity{}, fmt.Errorf("error fetching capacity: %v", err)
+	}
+
+	return capacity, nil
+}
+
+func (r *registry) lookup(handle string) (garden.Container, error) {
+	c, err := r.control.Lookup(handle)
+	if err != nil {
+		return nil, fmt.Errorf("error looking up container %q: %v", handle, err)
+	}
+
+	return c, nil
+}
+
+func (r *registry) stopContainer(handle string, kill bool) error {
+	c, err := r.lookup(handle)
+	if err != nil {
+		return err
+	}
+
+	if err := c.Stop(kill); err != nil {
+		return fmt.Errorf("error stopping container %q: %v", handle, err)
+	}
+
 	return nil
 }
@@ -0,0 +1,180 @@
+package garden
+
+import (
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"code.cloudfoundry.org/garden"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type fakeContainer struct {
+	garden.Container
+	handle string
+}
+
+func (c fakeContainer) Handle() string { return c.handle }
+func (c fakeContainer) CurrentMemoryLimits() (garden.MemoryLimits, error) {
+	return garden.MemoryLimits{}, nil
+}
+func (c fakeContainer) CurrentCPULimits() (garden.CPULimits, error) { return garden.CPULimits{}, nil }
+func (c fakeContainer) CurrentDiskLimits() (garden.DiskLimits, error) {
+	return garden.DiskLimits{}, nil
+}
+func (c fakeContainer) CurrentBandwidthLimits() (garden.BandwidthLimits, error) {
+	return garden.BandwidthLimits{}, nil
+}
+
+// flakyContainer fails to fetch its memory limit as long as failures is
+// positive.
+type flakyContainer struct {
+	fakeContainer
+	failures *int
+}
+
+func (c flakyContainer) CurrentMemoryLimits() (garden.MemoryLimits, error) {
+	if *c.failures > 0 {
+		*c.failures--
+		return garden.MemoryLimits{}, errors.New("connection reset")
+	}
+	return garden.MemoryLimits{LimitInBytes: 1024}, nil
+}
+
+type fakeClient struct {
+	garden.Client
+	containers []garden.Container
+	infos      map[string]garden.ContainerInfoEntry
+	metrics    map[string]garden.ContainerMetricsEntry
+	hang       chan struct{}
+	hangs      int32
+}
+
+func (c *fakeClient) Containers(garden.Properties) ([]garden.Container, error) {
+	return c.containers, nil
+}
+
+func (c *fakeClient) BulkInfo([]string) (map[string]garden.ContainerInfoEntry, error) {
+	return c.infos, nil
+}
+
+func (c *fakeClient) BulkMetrics([]string) (map[string]garden.ContainerMetricsEntry, error) {
+	if atomic.AddInt32(&c.hangs, -1) >= 0 {
+		<-c.hang
+	}
+	return c.metrics, nil
+}
+
+var _ = Describe("registry", func() {
+	var client *fakeClient
+
+	BeforeEach(func() {
+		client = &fakeClient{
+			hang: make(chan struct{}),
+			containers: []garden.Container{
+				fakeContainer{handle: "a"},
+				fakeContainer{handle: "b"},
+				fakeContainer{handle: "c"},
+			},
+			infos: map[string]garden.ContainerInfoEntry{
+				"a": {Info: garden.ContainerInfo{State: "active"}},
+				"b": {Err: garden.NewError("container vanished")},
+				"c": {Info: garden.ContainerInfo{State: "active"}},
+			},
+			metrics: map[string]garden.ContainerMetricsEntry{
+				"a": {},
+				"b": {},
+				"c": {},
+			},
+		}
+	})
+
+	AfterEach(func() {
+		close(client.hang)
+	})
+
+	It("skips containers that fail and walks the rest", func() {
+		var walked []string
+
+		errs, err := newRegistry(client, client, 2, time.Second, time.Second).walkContainers(func(e containerEntry) error {
+			walked = append(walked, e.container.Handle())
+			if e.container.Handle() == "c" {
+				return errors.New("boom")
+			}
+			return nil
+		})
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(walked).To(Equal([]string{"a", "c"}))
+		Expect(errs).To(HaveLen(2))
+		Expect(errs[0].Error()).To(ContainSubstring("container vanished"))
+		Expect(errs[1].Error()).To(Equal("boom"))
+	})
+
+	It("reports containers with their last known data when fetching times out", func() {
+		client.infos["b"] = garden.ContainerInfoEntry{}
+		r := newRegistry(client, client, 2, 50*time.Millisecond, 50*time.Millisecond)
+
+		_, err := r.walkContainers(func(containerEntry) error { return nil })
+		Expect(err).ToNot(HaveOccurred())
+
+		atomic.StoreInt32(&client.hangs, 1)
+
+		var stale []string
+		errs, err := r.walkContainers(func(e containerEntry) error {
+			if e.stale {
+				stale = append(stale, e.container.Handle())
+			}
+			return nil
+		})
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(errs).To(BeEmpty())
+		Expect(stale).To(Equal([]string{"a", "b", "c"}))
+	})
+
+	It("keeps refreshing while a previous call never returns", func() {
+		r := newRegistry(client, client, 2, 50*time.Millisecond, 50*time.Millisecond)
+		stale := func() []string {
+			var stale []string
+			_, err := r.walkContainers(func(e containerEntry) error {
+				if e.stale {
+					stale = append(stale, e.container.Handle())
+				}
+				return nil
+			})
+			Expect(err).ToNot(HaveOccurred())
+			return stale
+		}
+
+		Expect(stale()).To(BeEmpty())
+
+		atomic.StoreInt32(&client.hangs, 1)
+		Expect(stale()).To(Equal([]string{"a", "c"}))
+		Expect(stale()).To(BeEmpty())
+	})
+
+	It("only caches limits that were fetched completely", func() {
+		failures := 1
+		client.containers = []garden.Container{flakyContainer{fakeContainer{handle: "a"}, &failures}}
+		r := newRegistry(client, client, 1, time.Second, time.Second)
+
+		memoryLimit := func() uint64 {
+			var limit uint64
+			_, err := r.walkContainers(func(e containerEntry) error {
+				limit = e.limits.memory.LimitInBytes
+				return nil
+			})
+			Expect(err).ToNot(HaveOccurred())
+			return limit
+		}
+
+		Expect(memoryLimit()).To(BeZero())
+		Expect(memoryLimit()).To(Equal(uint64(1024)))
+
+		failures = 1
+		Expect(memoryLimit()).To(Equal(uint64(1024)))
+		Expect(failures).To(Equal(1))
+	})
+})
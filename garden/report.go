@@ -4,8 +4,6 @@ import (
 	"fmt"
 	"math/rand"
 	"time"
-
-	"code.cloudfoundry.org/garden"
 )
 
 const (
@@ -17,7 +15,18 @@ const (
 	ContainerHostIP           = "garden_container_host_ip"
 	ContainerExternalIP       = "garden_container_external_ip"
 	ContainerState            = "garden_container_state"
+	ContainerMemoryLimit      = "garden_container_memory_limit"
+	ContainerCPUShares        = "garden_container_cpu_shares"
+	ContainerDiskLimit        = "garden_container_disk_limit"
+	ContainerInodeLimit       = "garden_container_inode_limit"
+	ContainerBandwidthLimit   = "garden_container_bandwidth_limit"
+	ContainerBandwidthBurst   = "garden_container_bandwidth_burst"
+	ContainerStale            = "garden_container_stale"
+	ContainerLastEvent        = "garden_container_last_event"
+	ContainerOOMKilled        = "garden_container_oom_killed"
+	ContainerEventsPrefix     = "garden_container_events_"
 	ContainerPropertiesPrefix = "garden_container_properties_"
+	ContainerVolumesPrefix    = "garden_container_volumes_"
 	ContainerConcoursePrefix  = "concourse_"
 
 	DockerContainerHostname  = "docker_container_hostname"
@@ -25,28 +34,70 @@ const (
 	DockerContainerIPs       = "docker_container_ips"
 	DockerContainerNetworks  = "docker_container_networks"
 
-	MemoryUsage = "garden_memory_usage"
-	DiskUsage   = "garden_disk_usage"
-	CPUUsage    = "garden_cpu_total_usage"
-	NetworkRx   = "garden_network_rx"
-	NetworkTx   = "garden_network_tx"
+	MemoryUsage      = "garden_memory_usage"
+	MemoryLimitUsage = "garden_memory_limit_usage_percent"
+	DiskUsage        = "garden_disk_usage"
+	CPUUsage         = "garden_cpu_total_usage"
+	NetworkRx        = "garden_network_rx"
+	NetworkTx        = "garden_network_tx"
+
+	CPUPercent    = "garden_cpu_usage_percent"
+	NetworkRxRate = "garden_network_rx_rate"
+	NetworkTxRate = "garden_network_tx_rate"
+
+	HostMemoryCapacity    = "garden_host_memory_capacity"
+	HostDiskCapacity      = "garden_host_disk_capacity"
+	HostMaxContainers     = "garden_host_max_containers"
+	HostContainerCapacity = "garden_host_container_capacity"
+	HostContainers        = "garden_host_containers"
+	HostMemoryUsage       = "garden_host_memory_usage"
+	HostDiskUsage         = "garden_host_disk_usage"
+	HostCPUUsage          = "garden_host_cpu_total_usage"
+	HostNetworkRx         = "garden_host_network_rx"
+	HostNetworkTx         = "garden_host_network_tx"
+	HostCPUPercent        = "garden_host_cpu_usage_percent"
+	HostNetworkRxRate     = "garden_host_network_rx_rate"
+	HostNetworkTxRate     = "garden_host_network_tx_rate"
+
+	ProcessID          = "garden_process_id"
+	ProcessContainer   = "garden_process_container"
+	ProcessHostPID     = "garden_process_host_pid"
+	ProcessCmdline     = "garden_process_cmdline"
+	ProcessMemoryUsage = "garden_process_memory_usage"
+	ProcessCPUTime     = "garden_process_cpu_time"
 )
 
-func newReport(hostname string, appNameLookup lookupFn) report {
-	return report{
-		ID:                       fmt.Sprintf("%d", rand.Int63()),
-		Plugins:                  []pluginSpec{pluginInfo},
-		Container:                newContainer(),
-		ContainerImage:           newContainerImage(),
-		hostname:                 hostname,
-		lookupConcourseContainer: appNameLookup,
+func newReport(hostname string, enricher chain, properties propertyFilter, processes processSource, counters *counters, events *eventLog) report {
+	r := report{
+		ID:                fmt.Sprintf("%d", rand.Int63()),
+		Plugins:           []pluginSpec{pluginInfo},
+		Container:         newContainer(),
+		ContainerImage:    newContainerImage(),
+		Process:           newProcess(),
+		Host:              newHost(),
+		ConcoursePipeline: newConcourseTopology("pipeline", "pipelines", pipelineMetadataTemplates),
+		ConcourseJob:      newConcourseTopology("job", "jobs", jobMetadataTemplates),
+		ConcourseBuild:    newConcourseTopology("build", "builds", buildMetadataTemplates),
+		ConcourseWorker:   newConcourseTopology("worker", "workers", workerMetadataTemplates),
+		ConcourseVolume:   newConcourseTopology("volume", "volumes", volumeMetadataTemplates),
+		hostname:          hostname,
+		enricher:          enricher,
+		properties:        properties,
+		processes:         processes,
+		counters:          counters,
+		events:            events,
 	}
+
+	return r
 }
 
-func (r *report) AddNode(c garden.Container) error {
-	id := c.Handle()
+func (r *report) AddNode(e containerEntry) error {
+	id := e.container.Handle()
+	info := e.info
+	metrics := e.metrics
+	limits := e.limits
 
-	host := fmt.Sprintf("%s;<host>", r.hostname)
+	host := hostNodeID(r.hostname)
 
 	n := nodeSpec{
 		ID:       fmt.Sprintf("%s;<container>", id),
@@ -54,11 +105,6 @@ func (r *report) AddNode(c garden.Container) error {
 		Parents:  map[string][]string{"host": []string{host}},
 	}
 
-	info, err := c.Info()
-	if err != nil {
-		return fmt.Errorf("error retrieving info for container %q: %v", id, err)
-	}
-
 	n.Latest = map[string]latestSpec{
 		DockerContainerHostname: latest(r.hostname),
 		ContainerID:             latest(id),
@@ -69,59 +115,93 @@ func (r *report) AddNode(c garden.Container) error {
 		ContainerExternalIP:     latest(info.ExternalIP),
 	}
 
-	for k, v := range info.Properties {
-		key := fmt.Sprintf("%s%s", ContainerPropertiesPrefix, k)
-		n.Latest[key] = latest(v)
+	if e.stale {
+		n.Latest[ContainerStale] = latest("true")
 	}
 
+	n.LatestControls = containerControls(info.State)
+
+	events := r.events.observe(id, info.Events, time.Now())
+	r.addEvents(&n, events)
+
+	r.addProcessNodes(id, n.ID, host, info.ProcessIDs)
+
+	r.addProperties(&n, r.properties.filterAll(info.Properties))
+
 	n.Sets = map[string][]string{
 		DockerContainerIPsScopes: {fmt.Sprintf(";%s", info.ContainerIP)},
 		DockerContainerIPs:       {info.ContainerIP},
 		DockerContainerNetworks:  {CFContainerNetwork},
 	}
 
-	metrics, err := c.Metrics()
-	if err != nil {
-		return fmt.Errorf("error retrieving metrics for container %q: %v", id, err)
+	r.usage.add(metrics)
+
+	for k, v := range limits.latest() {
+		n.Latest[k] = v
 	}
 
 	n.Metrics = map[string]metricSpec{
-		CPUUsage:    metric(float64(metrics.CPUStat.Usage) / float64(time.Second)),
-		MemoryUsage: metric(float64(metrics.MemoryStat.TotalUsageTowardLimit/(1024*1024)) * 1000000),
-		DiskUsage:   metric(float64(metrics.DiskStat.TotalBytesUsed)),
+		CPUUsage:    metric(cpuSeconds(metrics.CPUStat.Usage)),
+		MemoryUsage: metricWithMax(float64(metrics.MemoryStat.TotalUsageTowardLimit), float64(limits.memory.LimitInBytes)),
+		DiskUsage:   metricWithMax(float64(metrics.DiskStat.TotalBytesUsed), float64(limits.disk.ByteHard)),
 		NetworkRx:   metric(float64(metrics.NetworkStat.RxBytes)),
 		NetworkTx:   metric(float64(metrics.NetworkStat.TxBytes)),
 	}
 
-	var stepName string
-	concourseContainer, found := r.lookupConcourseContainer(id)
-
-	if !found {
-		stepName = "not-found"
+	if limits.memory.LimitInBytes > 0 {
+		usage := float64(metrics.MemoryStat.TotalUsageTowardLimit) * 100 / float64(limits.memory.LimitInBytes)
+		n.Metrics[MemoryLimitUsage] = metricWithMax(usage, 100)
 	}
 
-	stepName = concourseContainer.StepName
+	rates, hasRates := r.observeRates(e)
+	if hasRates {
+		r.usage.addRates(rates)
+
+		bandwidth := float64(limits.bandwidth.RateInBytesPerSecond)
+
+		n.Metrics[CPUPercent] = metricWithMax(rates.cpuPercent, 100)
+		n.Metrics[NetworkRxRate] = metricWithMax(rates.rxPerSec, bandwidth)
+		n.Metrics[NetworkTxRate] = metricWithMax(rates.txPerSec, bandwidth)
+	}
 
 	containerName := id
-	if stepName != "not-found" {
-		containerName = fmt.Sprintf("%s/%s", stepName, id[:5])
+	imageName := "not-found"
+
+	enrichment, enriched := r.enricher.Enrich(id, info)
+	if enriched {
+		enrichment = r.properties.scrub(enrichment, info.Properties)
+
+		if enrichment.Name != "" {
+			containerName = enrichment.Name
+		}
+
+		if enrichment.Image != "" {
+			imageName = enrichment.Image
+		}
+
+		r.addEnrichment(&n, enrichment)
+	}
+
+	for topology, parents := range r.enricher.addParents(r, host, id) {
+		n.Parents[topology] = parents
 	}
 
-	n.Latest[fmt.Sprintf("%s%s", ContainerConcoursePrefix, "build number")] = latest(concourseContainer.BuildName)
-	n.Latest[fmt.Sprintf("%s%s", ContainerConcoursePrefix, "pipeline")] = latest(concourseContainer.PipelineName)
-	n.Latest[fmt.Sprintf("%s%s", ContainerConcoursePrefix, "job")] = latest(concourseContainer.JobName)
-	n.Latest[fmt.Sprintf("%s%s", ContainerConcoursePrefix, "type")] = latest(concourseContainer.Type)
+	r.addSample(e, enrichment.Labels, rates, hasRates)
+
+	if _, oom := events[oomEvent]; oom {
+		containerName = fmt.Sprintf("%s (OOM)", containerName)
+	}
 
 	n.Latest["docker_container_name"] = latest(containerName)
-	n.Latest["docker_image_id"] = latest(stepName)
+	n.Latest["docker_image_id"] = latest(imageName)
 
 	img := nodeSpec{
-		ID:       fmt.Sprintf("%s;<container_image>", stepName),
+		ID:       fmt.Sprintf("%s;<container_image>", imageName),
 		Topology: "container_image",
 		Parents:  map[string][]string{"host": []string{host}},
 		Latest: map[string]latestSpec{
-			"docker_image_id":   latest(stepName),
-			"docker_image_name": latest(stepName),
+			"docker_image_id":   latest(imageName),
+			"docker_image_name": latest(imageName),
 			"host_node_id":      latest(host),
 		},
 	}
@@ -131,9 +211,33 @@ func (r *report) AddNode(c garden.Container) error {
 	n.Parents["container_image"] = []string{img.ID}
 
 	r.Container.Nodes[n.ID] = n
+	r.events.remember(id, n)
 	return nil
 }
 
+// observeRates returns the rates of a container since the previous refresh.
+// Stale containers repeat old counters, so they have no rates.
+func (r *report) observeRates(e containerEntry) (rates, bool) {
+	if e.stale {
+		return rates{}, false
+	}
+
+	return r.counters.observe(e.container.Handle(), e.metrics, time.Now())
+}
+
+// withStatus returns a copy of the report with the given plugin status,
+// which Scope shows in its UI.
+func (r report) withStatus(status string) report {
+	plugins := make([]pluginSpec, len(r.Plugins))
+	for i, p := range r.Plugins {
+		p.Status = status
+		plugins[i] = p
+	}
+
+	r.Plugins = plugins
+	return r
+}
+
 func newContainerImage() containerImageSpec {
 	return containerImageSpec{
 		Label:          "image",
@@ -149,13 +253,32 @@ func newContainer() containerSpec {
 		Label:             "container",
 		LabelPlural:       "containers",
 		Shape:             "hexagon",
-		MetadataTemplates: containerMetadataTemplates,
+		MetadataTemplates: copyMetadataTemplates(containerMetadataTemplates),
 		MetricTemplates:   containerMetricTemplates,
-		TableTemplates:    containerTableTemplates,
+		TableTemplates:    copyTableTemplates(containerTableTemplates),
+		Controls:          containerControlSpecs,
 		Nodes:             map[string]nodeSpec{},
 	}
 }
 
+// copyMetadataTemplates copies templates so that enrichments can add to them
+// without affecting other reports.
+func copyMetadataTemplates(templates map[string]metadataTemplateSpec) map[string]metadataTemplateSpec {
+	c := make(map[string]metadataTemplateSpec, len(templates))
+	for k, v := range templates {
+		c[k] = v
+	}
+	return c
+}
+
+func copyTableTemplates(templates map[string]tableTemplateSpec) map[string]tableTemplateSpec {
+	c := make(map[string]tableTemplateSpec, len(templates))
+	for k, v := range templates {
+		c[k] = v
+	}
+	return c
+}
+
 func latest(v string) latestSpec {
 	return latestSpec{
 		Timestamp: time.Now(),
@@ -164,27 +287,40 @@ func latest(v string) latestSpec {
 }
 
 func metric(value float64) metricSpec {
+	return metricWithMax(value, value)
+}
+
+func metricWithMax(value, max float64) metricSpec {
 	now := time.Now()
 
+	if max < value {
+		max = value
+	}
+
 	return metricSpec{
 		Samples: []sampleSpec{sampleSpec{
 			Timestamp: now,
 			Value:     value,
 		}},
 		Min:   0,
-		Max:   value,
+		Max:   max,
 		First: now,
 		Last:  now,
 	}
 }
 
+func cpuSeconds(usage uint64) float64 {
+	return float64(usage) / float64(time.Second)
+}
+
 type nodeSpec struct {
-	ID       string                `json:"id"`
-	Topology string                `json:"topology,omitempty"`
-	Latest   map[string]latestSpec `json:"latest,omitempty"`
-	Metrics  map[string]metricSpec `json:"metrics,omitempty"`
-	Sets     map[string][]string   `json:"sets,omitempty"`
-	Parents  map[string][]string   `json:"parents,omitempty"`
+	ID             string                      `json:"id"`
+	Topology       string                      `json:"topology,omitempty"`
+	Latest         map[string]latestSpec       `json:"latest,omitempty"`
+	LatestControls map[string]controlEntrySpec `json:"latestControls,omitempty"`
+	Metrics        map[string]metricSpec       `json:"metrics,omitempty"`
+	Sets           map[string][]string         `json:"sets,omitempty"`
+	Parents        map[string][]string         `json:"parents,omitempty"`
 }
 
 type latestSpec struct {
@@ -192,6 +328,15 @@ type latestSpec struct {
 	Value     string    `json:"value"`
 }
 
+type controlEntrySpec struct {
+	Timestamp time.Time       `json:"timestamp"`
+	Value     controlDataSpec `json:"value"`
+}
+
+type controlDataSpec struct {
+	Dead bool `json:"dead"`
+}
+
 type metricSpec struct {
 	Samples []sampleSpec `json:"samples"`
 	Min     float64      `json:"min"`
@@ -220,6 +365,13 @@ type metricTemplateSpec struct {
 	Priority int    `json:"priority"`
 }
 
+type controlSpec struct {
+	ID    string `json:"id"`
+	Human string `json:"human"`
+	Icon  string `json:"icon"`
+	Rank  int    `json:"rank"`
+}
+
 type tableTemplateSpec struct {
 	ID     string `json:"id"`
 	Label  string `json:"label"`
@@ -232,6 +384,7 @@ type containerSpec struct {
 	MetadataTemplates map[string]metadataTemplateSpec `json:"metadata_templates"`
 	MetricTemplates   map[string]metricTemplateSpec   `json:"metric_templates"`
 	TableTemplates    map[string]tableTemplateSpec    `json:"table_templates"`
+	Controls          map[string]controlSpec          `json:"controls"`
 	Nodes             map[string]nodeSpec             `json:"nodes"`
 	Shape             string                          `json:"shape"`
 }
@@ -245,21 +398,45 @@ type containerImageSpec struct {
 	TableTemplates    map[string]tableTemplateSpec    `json:"table_templates"`
 }
 
+type topologySpec struct {
+	Label             string                          `json:"label"`
+	LabelPlural       string                          `json:"label_plural"`
+	MetadataTemplates map[string]metadataTemplateSpec `json:"metadata_templates,omitempty"`
+	MetricTemplates   map[string]metricTemplateSpec   `json:"metric_templates,omitempty"`
+	TableTemplates    map[string]tableTemplateSpec    `json:"table_templates,omitempty"`
+	Nodes             map[string]nodeSpec             `json:"nodes"`
+	Shape             string                          `json:"shape"`
+}
+
 type pluginSpec struct {
 	ID          string   `json:"id"`
 	Label       string   `json:"label"`
 	Description string   `json:"description"`
 	Interfaces  []string `json:"interfaces"`
 	APIVersion  string   `json:"api_version"`
+	Status      string   `json:"status,omitempty"`
 }
 
 type report struct {
-	ID                       string             `json:"ID"`
-	Plugins                  []pluginSpec       `json:"Plugins"`
-	Container                containerSpec      `json:"Container"`
-	ContainerImage           containerImageSpec `json:"ContainerImage"`
-	hostname                 string
-	lookupConcourseContainer lookupFn
+	ID                string             `json:"ID"`
+	Plugins           []pluginSpec       `json:"Plugins"`
+	Container         containerSpec      `json:"Container"`
+	ContainerImage    containerImageSpec `json:"ContainerImage"`
+	Process           topologySpec       `json:"Process"`
+	Host              topologySpec       `json:"Host"`
+	ConcoursePipeline topologySpec       `json:"ConcoursePipeline"`
+	ConcourseJob      topologySpec       `json:"ConcourseJob"`
+	ConcourseBuild    topologySpec       `json:"ConcourseBuild"`
+	ConcourseWorker   topologySpec       `json:"ConcourseWorker"`
+	ConcourseVolume   topologySpec       `json:"ConcourseVolume"`
+	hostname          string
+	enricher          chain
+	properties        propertyFilter
+	processes         processSource
+	samples           []containerSample
+	usage             hostUsage
+	counters          *counters
+	events            *eventLog
 }
 
 var (
@@ -267,7 +444,7 @@ var (
 		ID:          "garden",
 		Label:       "garden",
 		Description: "Reports on Garden containers running on the host",
-		Interfaces:  []string{"reporter"},
+		Interfaces:  []string{"reporter", "controller"},
 		APIVersion:  "1",
 	}
 
@@ -278,19 +455,40 @@ var (
 		ContainerIP:         {ID: ContainerIP, Label: "Container IP", From: "latest", Priority: 4},
 		ContainerHostIP:     {ID: ContainerHostIP, Label: "Host IP", From: "latest", Priority: 5},
 		ContainerExternalIP: {ID: ContainerExternalIP, Label: "External IP", From: "latest", Priority: 6},
+
+		ContainerMemoryLimit:    {ID: ContainerMemoryLimit, Label: "Memory Limit", From: "latest", Priority: 7},
+		ContainerCPUShares:      {ID: ContainerCPUShares, Label: "CPU Shares", From: "latest", Priority: 8},
+		ContainerDiskLimit:      {ID: ContainerDiskLimit, Label: "Disk Limit", From: "latest", Priority: 9},
+		ContainerInodeLimit:     {ID: ContainerInodeLimit, Label: "Inode Limit", From: "latest", Priority: 10},
+		ContainerBandwidthLimit: {ID: ContainerBandwidthLimit, Label: "Bandwidth Limit", From: "latest", Priority: 11},
+		ContainerBandwidthBurst: {ID: ContainerBandwidthBurst, Label: "Bandwidth Burst", From: "latest", Priority: 12},
+
+		ContainerLastEvent: {ID: ContainerLastEvent, Label: "Last Event", From: "latest", Priority: 13},
+		ContainerOOMKilled: {ID: ContainerOOMKilled, Label: "OOM Killed", From: "latest", Priority: 14},
+		ContainerStale:     {ID: ContainerStale, Label: "Stale", From: "latest", Priority: 15},
 	}
 
 	containerMetricTemplates = map[string]metricTemplateSpec{
-		CPUUsage:    {ID: CPUUsage, Label: "CPU Usage", Format: "", Priority: 3},
-		MemoryUsage: {ID: MemoryUsage, Label: "Memory", Format: "filesize", Priority: 4},
-		DiskUsage:   {ID: DiskUsage, Label: "Disk Usage", Format: "filesize", Priority: 5},
-		NetworkRx:   {ID: NetworkRx, Label: "Network RX", Format: "filesize", Priority: 6},
-		NetworkTx:   {ID: NetworkTx, Label: "Network TX", Format: "filesize", Priority: 7},
+		CPUPercent:       {ID: CPUPercent, Label: "CPU", Format: "percent", Priority: 1},
+		MemoryUsage:      {ID: MemoryUsage, Label: "Memory", Format: "filesize", Priority: 2},
+		MemoryLimitUsage: {ID: MemoryLimitUsage, Label: "Memory (of limit)", Format: "percent", Priority: 3},
+		NetworkRxRate:    {ID: NetworkRxRate, Label: "Network RX/s", Format: "filesize", Priority: 4},
+		NetworkTxRate:    {ID: NetworkTxRate, Label: "Network TX/s", Format: "filesize", Priority: 5},
+		DiskUsage:        {ID: DiskUsage, Label: "Disk Usage", Format: "filesize", Priority: 6},
+		CPUUsage:         {ID: CPUUsage, Label: "CPU Time (total)", Format: "", Priority: 7},
+		NetworkRx:        {ID: NetworkRx, Label: "Network RX (total)", Format: "filesize", Priority: 8},
+		NetworkTx:        {ID: NetworkTx, Label: "Network TX (total)", Format: "filesize", Priority: 9},
 	}
 
 	containerTableTemplates = map[string]tableTemplateSpec{
-		ContainerPropertiesPrefix: {ID: ContainerPropertiesPrefix, Label: "Properties", Prefix: ContainerPropertiesPrefix},
-		ContainerConcoursePrefix:  {ID: ContainerConcoursePrefix, Label: "Concourse", Prefix: ContainerConcoursePrefix},
+		ContainerEventsPrefix:  {ID: ContainerEventsPrefix, Label: "Events", Prefix: ContainerEventsPrefix},
+		ContainerVolumesPrefix: {ID: ContainerVolumesPrefix, Label: "Concourse Volumes", Prefix: ContainerVolumesPrefix},
+	}
+
+	containerControlSpecs = map[string]controlSpec{
+		StopContainer: {ID: StopContainer, Human: "Stop", Icon: "fa fa-stop", Rank: 1},
+		KillContainer: {ID: KillContainer, Human: "Kill", Icon: "fa fa-times", Rank: 2},
+		ExecShell:     {ID: ExecShell, Human: "Exec shell", Icon: "fa fa-terminal", Rank: 0},
 	}
 
 	containerImageTableTemplates = map[string]tableTemplateSpec{
@@ -298,4 +496,90 @@ var (
 	}
 
 	containerImageMetadataTemplates = map[string]metadataTemplateSpec{}
+
+	hostMetadataTemplates = map[string]metadataTemplateSpec{
+		HostContainerCapacity: {ID: HostContainerCapacity, Label: "Garden Containers", From: "latest", Priority: 1},
+		HostMaxContainers:     {ID: HostMaxContainers, Label: "Max Containers", From: "latest", Priority: 2},
+		HostMemoryCapacity:    {ID: HostMemoryCapacity, Label: "Garden Memory", From: "latest", Priority: 3},
+		HostDiskCapacity:      {ID: HostDiskCapacity, Label: "Garden Disk", From: "latest", Priority: 4},
+
+		WorkerState:            {ID: WorkerState, Label: "Worker State", From: "latest", Priority: 5},
+		WorkerPlatform:         {ID: WorkerPlatform, Label: "Worker Platform", From: "latest", Priority: 6},
+		WorkerTags:             {ID: WorkerTags, Label: "Worker Tags", From: "latest", Priority: 7},
+		WorkerTeam:             {ID: WorkerTeam, Label: "Worker Team", From: "latest", Priority: 8},
+		WorkerVersion:          {ID: WorkerVersion, Label: "Worker Version", From: "latest", Priority: 9},
+		WorkerStartTime:        {ID: WorkerStartTime, Label: "Worker Started", From: "latest", Priority: 10},
+		WorkerEphemeral:        {ID: WorkerEphemeral, Label: "Worker Ephemeral", From: "latest", Priority: 11},
+		WorkerActiveContainers: {ID: WorkerActiveContainers, Label: "Worker Active Containers", From: "latest", Priority: 12},
+		WorkerActiveVolumes:    {ID: WorkerActiveVolumes, Label: "Worker Active Volumes", From: "latest", Priority: 13},
+	}
+
+	hostMetricTemplates = map[string]metricTemplateSpec{
+		HostContainers:    {ID: HostContainers, Label: "Garden Containers", Format: "", Priority: 1},
+		HostCPUPercent:    {ID: HostCPUPercent, Label: "Garden CPU", Format: "percent", Priority: 2},
+		HostMemoryUsage:   {ID: HostMemoryUsage, Label: "Garden Memory", Format: "filesize", Priority: 3},
+		HostDiskUsage:     {ID: HostDiskUsage, Label: "Garden Disk Usage", Format: "filesize", Priority: 4},
+		HostNetworkRxRate: {ID: HostNetworkRxRate, Label: "Garden Network RX/s", Format: "filesize", Priority: 5},
+		HostNetworkTxRate: {ID: HostNetworkTxRate, Label: "Garden Network TX/s", Format: "filesize", Priority: 6},
+		HostCPUUsage:      {ID: HostCPUUsage, Label: "Garden CPU Time (total)", Format: "", Priority: 7},
+		HostNetworkRx:     {ID: HostNetworkRx, Label: "Garden Network RX (total)", Format: "filesize", Priority: 8},
+		HostNetworkTx:     {ID: HostNetworkTx, Label: "Garden Network TX (total)", Format: "filesize", Priority: 9},
+	}
+
+	pipelineMetadataTemplates = map[string]metadataTemplateSpec{
+		TeamName:     {ID: TeamName, Label: "Team", From: "latest", Priority: 0},
+		PipelineName: {ID: PipelineName, Label: "Pipeline", From: "latest", Priority: 1},
+	}
+
+	jobMetadataTemplates = map[string]metadataTemplateSpec{
+		TeamName:     {ID: TeamName, Label: "Team", From: "latest", Priority: 0},
+		PipelineName: {ID: PipelineName, Label: "Pipeline", From: "latest", Priority: 1},
+		JobName:      {ID: JobName, Label: "Job", From: "latest", Priority: 2},
+	}
+
+	buildMetadataTemplates = map[string]metadataTemplateSpec{
+		TeamName:     {ID: TeamName, Label: "Team", From: "latest", Priority: 0},
+		PipelineName: {ID: PipelineName, Label: "Pipeline", From: "latest", Priority: 1},
+		JobName:      {ID: JobName, Label: "Job", From: "latest", Priority: 2},
+		BuildName:    {ID: BuildName, Label: "Build", From: "latest", Priority: 3},
+		BuildID:      {ID: BuildID, Label: "Build ID", From: "latest", Priority: 4},
+	}
+
+	workerMetadataTemplates = map[string]metadataTemplateSpec{
+		WorkerName:             {ID: WorkerName, Label: "Name", From: "latest", Priority: 1},
+		WorkerState:            {ID: WorkerState, Label: "State", From: "latest", Priority: 2},
+		WorkerPlatform:         {ID: WorkerPlatform, Label: "Platform", From: "latest", Priority: 3},
+		WorkerTags:             {ID: WorkerTags, Label: "Tags", From: "latest", Priority: 4},
+		WorkerTeam:             {ID: WorkerTeam, Label: "Team", From: "latest", Priority: 5},
+		WorkerVersion:          {ID: WorkerVersion, Label: "Version", From: "latest", Priority: 6},
+		WorkerStartTime:        {ID: WorkerStartTime, Label: "Started", From: "latest", Priority: 7},
+		WorkerEphemeral:        {ID: WorkerEphemeral, Label: "Ephemeral", From: "latest", Priority: 8},
+		WorkerActiveContainers: {ID: WorkerActiveContainers, Label: "Active Containers", From: "latest", Priority: 9},
+		WorkerActiveVolumes:    {ID: WorkerActiveVolumes, Label: "Active Volumes", From: "latest", Priority: 10},
+	}
+
+	volumeMetadataTemplates = map[string]metadataTemplateSpec{
+		VolumeHandle:       {ID: VolumeHandle, Label: "Handle", From: "latest", Priority: 1},
+		VolumeType:         {ID: VolumeType, Label: "Type", From: "latest", Priority: 2},
+		VolumeResourceType: {ID: VolumeResourceType, Label: "Resource Type", From: "latest", Priority: 3},
+		VolumePipeline:     {ID: VolumePipeline, Label: "Pipeline", From: "latest", Priority: 4},
+		VolumeJob:          {ID: VolumeJob, Label: "Job", From: "latest", Priority: 5},
+		VolumeStep:         {ID: VolumeStep, Label: "Step", From: "latest", Priority: 6},
+		VolumeContainer:    {ID: VolumeContainer, Label: "Container", From: "latest", Priority: 7},
+		VolumeParent:       {ID: VolumeParent, Label: "Parent Volume", From: "latest", Priority: 8},
+		VolumePath:         {ID: VolumePath, Label: "Path", From: "latest", Priority: 9},
+		VolumeOrphaned:     {ID: VolumeOrphaned, Label: "Container Gone", From: "latest", Priority: 10},
+	}
+
+	processMetadataTemplates = map[string]metadataTemplateSpec{
+		ProcessID:        {ID: ProcessID, Label: "Garden Process ID", From: "latest", Priority: 1},
+		ProcessContainer: {ID: ProcessContainer, Label: "Container", From: "latest", Priority: 2},
+		ProcessCmdline:   {ID: ProcessCmdline, Label: "Command", From: "latest", Priority: 3},
+		ProcessHostPID:   {ID: ProcessHostPID, Label: "Host PID", From: "latest", Priority: 4},
+	}
+
+	processMetricTemplates = map[string]metricTemplateSpec{
+		ProcessCPUTime:     {ID: ProcessCPUTime, Label: "CPU Time", Format: "", Priority: 1},
+		ProcessMemoryUsage: {ID: ProcessMemoryUsage, Label: "Memory", Format: "filesize", Priority: 2},
+	}
 )
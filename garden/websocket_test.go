@@ -0,0 +1,201 @@
+package garden
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// fakeScope accepts a websocket handshake and hands the raw connection to
+// serve.
+type fakeScope struct {
+	accept string
+	header chan http.Header
+	serve  func(conn net.Conn, r *bufio.Reader)
+}
+
+func (s *fakeScope) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// serve asserts in the server's goroutine.
+	defer GinkgoRecover()
+
+	s.header <- r.Header
+
+	conn, rw, err := w.(http.Hijacker).Hijack()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	accept := s.accept
+	if accept == "" {
+		accept = acceptKey(r.Header.Get("Sec-WebSocket-Key"))
+	}
+
+	io.WriteString(conn, "HTTP/1.1 101 Switching Protocols\r\n"+
+		"Upgrade: websocket\r\n"+
+		"Connection: Upgrade\r\n"+
+		"Sec-WebSocket-Accept: "+accept+"\r\n\r\n")
+
+	s.serve(conn, rw.Reader)
+}
+
+// serverFrame encodes an unmasked frame as sent by a server.
+func serverFrame(fin bool, opcode byte, payload []byte) []byte {
+	head := opcode
+	if fin {
+		head |= 0x80
+	}
+
+	frame := []byte{head}
+	switch {
+	case len(payload) < 126:
+		frame = append(frame, byte(len(payload)))
+	case len(payload) <= 0xffff:
+		frame = append(frame, 126, 0, 0)
+		binary.BigEndian.PutUint16(frame[2:], uint16(len(payload)))
+	default:
+		frame = append(frame, 127, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(frame[2:], uint64(len(payload)))
+	}
+
+	return append(frame, payload...)
+}
+
+// readClientFrame decodes a frame sent by the client, which must be masked.
+func readClientFrame(r *bufio.Reader) (byte, []byte) {
+	var head [2]byte
+	_, err := io.ReadFull(r, head[:])
+	Expect(err).ToNot(HaveOccurred())
+	Expect(head[1] & 0x80).ToNot(BeZero())
+
+	length := int(head[1] & 0x7f)
+	Expect(length).To(BeNumerically("<", 126))
+
+	var mask [4]byte
+	_, err = io.ReadFull(r, mask[:])
+	Expect(err).ToNot(HaveOccurred())
+
+	payload := make([]byte, length)
+	_, err = io.ReadFull(r, payload)
+	Expect(err).ToNot(HaveOccurred())
+
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+
+	return head[0] & 0x0f, payload
+}
+
+var _ = Describe("websocket", func() {
+	var (
+		scope  *fakeScope
+		server *httptest.Server
+	)
+
+	BeforeEach(func() {
+		scope = &fakeScope{header: make(chan http.Header, 1)}
+		server = httptest.NewServer(scope)
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	url := func() string {
+		return strings.Replace(server.URL, "http://", "ws://", 1) + "/api/pipe/pipe-id/probe"
+	}
+
+	It("performs the handshake and exchanges masked frames", func() {
+		received := make(chan []byte, 1)
+		scope.serve = func(conn net.Conn, r *bufio.Reader) {
+			conn.Write(serverFrame(false, opBinary, []byte("hel")))
+			conn.Write(serverFrame(true, opContinuation, []byte("lo")))
+
+			op, payload := readClientFrame(r)
+			Expect(op).To(Equal(byte(opBinary)))
+			received <- payload
+		}
+
+		ws, err := dialWebsocket(url(), http.Header{"Authorization": {"Scope-Probe token=secret"}})
+		Expect(err).ToNot(HaveOccurred())
+		defer ws.Close()
+
+		header := <-scope.header
+		Expect(header.Get("Authorization")).To(Equal("Scope-Probe token=secret"))
+		Expect(header.Get("Upgrade")).To(Equal("websocket"))
+
+		buf := make([]byte, 16)
+		n, err := io.ReadAtLeast(ws, buf, 5)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(buf[:n])).To(Equal("hello"))
+
+		_, err = ws.Write([]byte("ls\n"))
+		Expect(err).ToNot(HaveOccurred())
+		Eventually(received).Should(Receive(Equal([]byte("ls\n"))))
+	})
+
+	It("answers pings and ends at a close frame", func() {
+		pong := make(chan []byte, 1)
+		scope.serve = func(conn net.Conn, r *bufio.Reader) {
+			conn.Write(serverFrame(true, opPing, []byte("ping")))
+			op, payload := readClientFrame(r)
+			Expect(op).To(Equal(byte(opPong)))
+			pong <- payload
+			conn.Write(serverFrame(true, opClose, nil))
+		}
+
+		ws, err := dialWebsocket(url(), nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = ws.Read(make([]byte, 16))
+		Expect(err).To(Equal(io.EOF))
+		Expect(pong).To(Receive(Equal([]byte("ping"))))
+	})
+
+	It("rejects an invalid accept key", func() {
+		scope.accept = "invalid"
+		scope.serve = func(net.Conn, *bufio.Reader) {}
+
+		_, err := dialWebsocket(url(), nil)
+		Expect(err).To(MatchError("invalid websocket handshake accept key"))
+	})
+
+	It("rejects frames claiming an oversized payload without allocating it", func() {
+		scope.serve = func(conn net.Conn, r *bufio.Reader) {
+			frame := []byte{0x80 | opBinary, 127, 0, 0, 0, 0, 0, 0, 0, 0}
+			binary.BigEndian.PutUint64(frame[2:], 1<<62)
+			conn.Write(frame)
+			r.ReadByte()
+		}
+
+		ws, err := dialWebsocket(url(), nil)
+		Expect(err).ToNot(HaveOccurred())
+		defer ws.Close()
+
+		_, err = ws.Read(make([]byte, 16))
+		Expect(err).To(Equal(errMessageTooLarge))
+	})
+
+	It("rejects messages that exceed the limit across fragments", func() {
+		chunk := make([]byte, maxMessageSize/2+1)
+		scope.serve = func(conn net.Conn, r *bufio.Reader) {
+			conn.Write(serverFrame(false, opBinary, chunk))
+			conn.Write(serverFrame(true, opContinuation, chunk))
+			r.ReadByte()
+		}
+
+		ws, err := dialWebsocket(url(), nil)
+		Expect(err).ToNot(HaveOccurred())
+		defer ws.Close()
+
+		_, err = ws.Read(make([]byte, 16))
+		Expect(err).To(Equal(errMessageTooLarge))
+	})
+})
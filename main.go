@@ -10,25 +10,62 @@ import (
 	"os/signal"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/st3v/scope-garden/conchhorse"
-	"github.com/st3v/scope-garden/garden"
 )
 
 var (
+	configFile            string
 	gardenNetwork         string
 	gardenAddr            string
 	gardenRefreshInterval time.Duration
+	gardenRefreshTimeout  time.Duration
+	containerTimeout      time.Duration
+	collectionWorkers     int
+	normalizeCPU          bool
+	gardenDepotDir        string
+	gardenCgroupRoot      string
+	propertiesAllow       string
+	propertiesDeny        string
+	propertiesRedact      string
+	propertiesMaxLength   int
 	pluginsRoot           string
 	hostname              string
+	scopeAppURL           string
+	scopeToken            string
 	atcUrl                string
 	atcUsername           string
 	atcPassword           string
+	atcPasswordFile       string
+	atcToken              string
+	atcFlyTarget          string
+	atcAuth               string
+	atcTeams              string
+	atcRefreshInterval    time.Duration
+	atcCACert             string
+	atcClientCert         string
+	atcClientKey          string
+	atcInsecure           bool
+	cfEnabled             bool
+	cfAPIURL              string
+	cfUAAURL              string
+	cfClientID            string
+	cfClientSecret        string
+	cfNameTTL             time.Duration
+	metricsAddr           string
 )
 
 func init() {
+	flag.StringVar(
+		&configFile,
+		"config",
+		getEnvString("CONFIG_FILE", ""),
+		"YAML config file overriding flags and environment variables, reloaded on SIGHUP [CONFIG_FILE]",
+	)
+
 	flag.StringVar(
 		&gardenNetwork,
 		"garden.network",
@@ -50,6 +87,76 @@ func init() {
 		"interval to fetch for container updates from garden server [GARDEN_REFRESH_INTERVAL]",
 	)
 
+	flag.DurationVar(
+		&gardenRefreshTimeout,
+		"garden.refresh-timeout",
+		getEnvDuration("GARDEN_REFRESH_TIMEOUT", 10*time.Second),
+		"deadline for fetching all containers from garden server during a refresh [GARDEN_REFRESH_TIMEOUT]",
+	)
+
+	flag.DurationVar(
+		&containerTimeout,
+		"garden.container-timeout",
+		getEnvDuration("GARDEN_CONTAINER_TIMEOUT", 2*time.Second),
+		"deadline for fetching a single container from garden server [GARDEN_CONTAINER_TIMEOUT]",
+	)
+
+	flag.IntVar(
+		&collectionWorkers,
+		"garden.workers",
+		getEnvInt("GARDEN_WORKERS", 8),
+		"number of containers fetched from garden server concurrently [GARDEN_WORKERS]",
+	)
+
+	flag.BoolVar(
+		&normalizeCPU,
+		"garden.normalize-cpu",
+		getEnvBool("GARDEN_NORMALIZE_CPU", false),
+		"report container CPU usage relative to all host cores instead of a single core [GARDEN_NORMALIZE_CPU]",
+	)
+
+	flag.StringVar(
+		&gardenDepotDir,
+		"garden.depot-dir",
+		getEnvString("GARDEN_DEPOT_DIR", "/var/vcap/data/garden/depot"),
+		"garden depot holding the pidfiles of container processes, empty to not map processes to host PIDs [GARDEN_DEPOT_DIR]",
+	)
+
+	flag.StringVar(
+		&gardenCgroupRoot,
+		"garden.cgroup-root",
+		getEnvString("GARDEN_CGROUP_ROOT", "/sys/fs/cgroup/memory/garden"),
+		"cgroup directory holding a sub-directory per container handle, empty to not map processes to host PIDs [GARDEN_CGROUP_ROOT]",
+	)
+
+	flag.StringVar(
+		&propertiesAllow,
+		"garden.properties.allow",
+		getEnvString("GARDEN_PROPERTIES_ALLOW", ""),
+		"Comma-separated globs or /regexps/ of property keys to report, all if empty [GARDEN_PROPERTIES_ALLOW]",
+	)
+
+	flag.StringVar(
+		&propertiesDeny,
+		"garden.properties.deny",
+		getEnvString("GARDEN_PROPERTIES_DENY", ""),
+		"Comma-separated globs or /regexps/ of property keys not to report [GARDEN_PROPERTIES_DENY]",
+	)
+
+	flag.StringVar(
+		&propertiesRedact,
+		"garden.properties.redact",
+		getEnvString("GARDEN_PROPERTIES_REDACT", ""),
+		"Comma-separated regexps of property values to redact [GARDEN_PROPERTIES_REDACT]",
+	)
+
+	flag.IntVar(
+		&propertiesMaxLength,
+		"garden.properties.max-length",
+		getEnvInt("GARDEN_PROPERTIES_MAX_LENGTH", 1024),
+		"Length after which Scope truncates property values, unlimited if 0 [GARDEN_PROPERTIES_MAX_LENGTH]",
+	)
+
 	flag.StringVar(
 		&pluginsRoot,
 		"plugins-root",
@@ -64,11 +171,25 @@ func init() {
 		"hostname as reported by scope [HOSTNAME]",
 	)
 
+	flag.StringVar(
+		&scopeAppURL,
+		"scope.app-url",
+		getEnvString("SCOPE_APP_URL", "http://localhost:4040"),
+		"URL of the scope app used to open terminal pipes [SCOPE_APP_URL]",
+	)
+
+	flag.StringVar(
+		&scopeToken,
+		"scope.token",
+		getEnvString("SCOPE_TOKEN", ""),
+		"token used to authenticate terminal pipes with the scope app [SCOPE_TOKEN]",
+	)
+
 	flag.StringVar(
 		&atcUrl,
 		"atc.url",
 		getEnvString("ATC_URL", ""),
-		"Protocol, hostname and port to access the ATC [ATC_URL]",
+		"Protocol, hostname and port to access the ATC, disables Concourse enrichment if empty [ATC_URL]",
 	)
 
 	flag.StringVar(
@@ -84,22 +205,138 @@ func init() {
 		getEnvString("ATC_PASSWORD", ""),
 		"Password for the ATC user [ATC_PASSWORD]",
 	)
+
+	flag.StringVar(
+		&atcPasswordFile,
+		"atc.password-file",
+		getEnvString("ATC_PASSWORD_FILE", ""),
+		"File containing the password for the ATC user [ATC_PASSWORD_FILE]",
+	)
+
+	flag.StringVar(
+		&atcToken,
+		"atc.token",
+		getEnvString("ATC_TOKEN", ""),
+		"Pre-issued bearer token for the ATC [ATC_TOKEN]",
+	)
+
+	flag.StringVar(
+		&atcFlyTarget,
+		"atc.fly-target",
+		getEnvString("ATC_FLY_TARGET", ""),
+		"Name of a fly target in ~/.flyrc to connect to the ATC with [ATC_FLY_TARGET]",
+	)
+
+	flag.StringVar(
+		&atcAuth,
+		"atc.auth",
+		getEnvString("ATC_AUTH", conchhorse.CredentialsPassword),
+		"Source of ATC credentials (password, password-file, token, flyrc) [ATC_AUTH]",
+	)
+
+	flag.StringVar(
+		&atcTeams,
+		"atc.teams",
+		getEnvString("ATC_TEAMS", ""),
+		"Comma-separated teams to look up containers for, all teams if empty [ATC_TEAMS]",
+	)
+
+	flag.DurationVar(
+		&atcRefreshInterval,
+		"atc.refresh-interval",
+		getEnvDuration("ATC_REFRESH_INTERVAL", 3*time.Second),
+		"Interval to fetch containers, workers and volumes from the ATC [ATC_REFRESH_INTERVAL]",
+	)
+
+	flag.StringVar(
+		&atcCACert,
+		"atc.ca-cert",
+		getEnvString("ATC_CA_CERT", ""),
+		"Path to a PEM bundle of CAs to trust for the ATC [ATC_CA_CERT]",
+	)
+
+	flag.StringVar(
+		&atcClientCert,
+		"atc.client-cert",
+		getEnvString("ATC_CLIENT_CERT", ""),
+		"Path to a PEM client certificate for mutual TLS with the ATC [ATC_CLIENT_CERT]",
+	)
+
+	flag.StringVar(
+		&atcClientKey,
+		"atc.client-key",
+		getEnvString("ATC_CLIENT_KEY", ""),
+		"Path to the PEM key of the client certificate [ATC_CLIENT_KEY]",
+	)
+
+	flag.BoolVar(
+		&atcInsecure,
+		"atc.insecure",
+		getEnvBool("ATC_INSECURE", false),
+		"Skip verification of the ATC's certificate [ATC_INSECURE]",
+	)
+
+	flag.BoolVar(
+		&cfEnabled,
+		"cf.enabled",
+		getEnvBool("CF_ENABLED", false),
+		"Enrich Diego app containers with Cloud Foundry metadata [CF_ENABLED]",
+	)
+
+	flag.StringVar(
+		&cfAPIURL,
+		"cf.api-url",
+		getEnvString("CF_API_URL", ""),
+		"Cloud Controller URL to resolve app, space and org names, GUIDs are shown if empty [CF_API_URL]",
+	)
+
+	flag.StringVar(
+		&cfUAAURL,
+		"cf.uaa-url",
+		getEnvString("CF_UAA_URL", ""),
+		"UAA URL to get Cloud Controller tokens from, looked up from the Cloud Controller if empty [CF_UAA_URL]",
+	)
+
+	flag.StringVar(
+		&cfClientID,
+		"cf.client-id",
+		getEnvString("CF_CLIENT_ID", ""),
+		"UAA client to authenticate with the Cloud Controller as, unauthenticated if empty [CF_CLIENT_ID]",
+	)
+
+	flag.StringVar(
+		&cfClientSecret,
+		"cf.client-secret",
+		getEnvString("CF_CLIENT_SECRET", ""),
+		"Secret of the UAA client [CF_CLIENT_SECRET]",
+	)
+
+	flag.DurationVar(
+		&cfNameTTL,
+		"cf.name-ttl",
+		getEnvDuration("CF_NAME_TTL", 5*time.Minute),
+		"Duration after which resolved Cloud Foundry names are fetched again [CF_NAME_TTL]",
+	)
+
+	flag.StringVar(
+		&metricsAddr,
+		"metrics.addr",
+		getEnvString("METRICS_ADDR", ""),
+		"TCP address to serve Prometheus metrics on at /metrics, disabled if empty [METRICS_ADDR]",
+	)
 }
 
 func main() {
 	flag.Parse()
 
-	if hostname == "" {
-		var err error
-		hostname, err = os.Hostname()
-		if err != nil {
-			log.Fatal(err)
-		}
+	c, err := loadConfig(configFile)
+	if err != nil {
+		log.Fatal(err)
 	}
 
-	log.Printf("Starting on %s...\n", hostname)
+	log.Printf("Starting on %s...\n", c.Hostname)
 
-	socket := filepath.Join(pluginsRoot, "garden", "garden.sock")
+	socket := filepath.Join(c.PluginsRoot, "garden", "garden.sock")
 
 	listener, err := listen(socket)
 	if err != nil {
@@ -113,24 +350,17 @@ func main() {
 
 	handleSignals()
 
-	client, err := conchhorse.NewClient(atcUrl, atcUsername, atcPassword)
+	s, err := newServer(c)
 	if err != nil {
 		log.Fatal(err)
 	}
+	defer s.Close()
 
-	appDir := conchhorse.NewAppDirectory(client, 3*time.Second)
-	defer appDir.Close()
-
-	plugin := garden.NewPlugin(
-		hostname,
-		gardenNetwork,
-		gardenAddr,
-		gardenRefreshInterval,
-		appDir.ConcourseContainer,
-	)
-	defer plugin.Close()
+	handleReload(s, c)
 
-	http.HandleFunc("/report", plugin.Report)
+	http.HandleFunc("/report", s.Report)
+	http.HandleFunc("/control", s.Control)
+	http.HandleFunc("/healthz", s.Healthz)
 
 	log.Fatal(http.Serve(listener, nil))
 }
@@ -163,6 +393,47 @@ func handleSignals() {
 	}()
 }
 
+// handleReload reconfigures the plugin from the reloaded configuration on
+// SIGHUP. The Scope socket is kept, so a change to the plugins root only
+// takes effect after a restart.
+func handleReload(s *server, initial config) {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	go func() {
+		for range hangup {
+			log.Println("Reloading configuration...")
+
+			c, err := loadConfig(configFile)
+			if err != nil {
+				log.Printf("error reloading configuration, keeping the current one: %v\n", err)
+				continue
+			}
+
+			if c.PluginsRoot != initial.PluginsRoot {
+				log.Printf("ignoring new plugins root %q until restart\n", c.PluginsRoot)
+			}
+
+			if err := s.reload(c); err != nil {
+				log.Printf("error reloading configuration, keeping the current one: %v\n", err)
+				continue
+			}
+
+			log.Println("Configuration reloaded")
+		}
+	}()
+}
+
+func splitList(s string) []string {
+	var list []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+
+	return list
+}
+
 func getEnvString(key, def string) string {
 	v := os.Getenv(key)
 	if v == "" {
@@ -186,6 +457,20 @@ func getEnvDuration(key string, def time.Duration) time.Duration {
 	return d
 }
 
+func getEnvInt(key string, def int) int {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		return def
+	}
+
+	return i
+}
+
 func getEnvBool(key string, def bool) bool {
 	v := os.Getenv(key)
 	if v == "" {
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"reflect"
+	"sync"
+
+	"github.com/concourse/go-concourse/concourse"
+	"github.com/st3v/scope-garden/cloudcontroller"
+	"github.com/st3v/scope-garden/conchhorse"
+	"github.com/st3v/scope-garden/garden"
+	"golang.org/x/oauth2"
+)
+
+type plugin interface {
+	Report(w http.ResponseWriter, r *http.Request)
+	Control(w http.ResponseWriter, r *http.Request)
+	Healthz(w http.ResponseWriter, r *http.Request)
+	Metrics(w http.ResponseWriter, r *http.Request)
+	Reconfigure(config garden.Config, enrichers ...garden.Enricher) error
+	Close()
+}
+
+// enrichers are the enrichers built from one configuration. The Concourse
+// enricher is kept across reloads with unchanged atc settings, so that its
+// app directory does not start over empty, and likewise the CF enricher.
+type enrichers struct {
+	atc       atcConfig
+	concourse garden.Enricher
+	closeATC  func()
+
+	cf         cfConfig
+	cfEnricher garden.Enricher
+}
+
+// newEnrichers builds the enrichers for c, reusing those of previous, if
+// not nil, whose settings did not change.
+func newEnrichers(c config, previous *enrichers) *enrichers {
+	e := &enrichers{atc: c.ATC, cf: c.CF}
+
+	if previous != nil && reflect.DeepEqual(previous.atc, c.ATC) {
+		e.concourse, e.closeATC = previous.concourse, previous.closeATC
+	} else if c.atcEnabled() {
+		credentials := c.credentials()
+		tlsConfig := c.tlsConfig()
+
+		appDir := conchhorse.ConnectAppDirectory(func() (concourse.Client, error) {
+			return conchhorse.NewClient(c.ATC.URL, credentials, tlsConfig)
+		}, c.ATC.RefreshInterval, c.ATC.Teams)
+
+		e.concourse, e.closeATC = garden.NewConcourseEnricher(appDir), appDir.Close
+	}
+
+	if e.concourse == nil {
+		log.Println("No ATC configured, Concourse enrichment is disabled")
+	}
+
+	if previous != nil && reflect.DeepEqual(previous.cf, c.CF) {
+		e.cfEnricher = previous.cfEnricher
+	} else if c.CF.Enabled {
+		var names garden.CFNames
+		if c.CF.APIURL != "" {
+			var tokens oauth2.TokenSource
+			if c.CF.ClientID != "" {
+				tokens = cloudcontroller.ClientCredentials(c.CF.APIURL, c.CF.UAAURL, c.CF.ClientID, c.CF.ClientSecret)
+			}
+
+			names = cloudcontroller.NewClient(c.CF.APIURL, tokens, c.CF.NameTTL)
+		}
+
+		e.cfEnricher = garden.NewCFEnricher(names)
+	}
+
+	return e
+}
+
+func (e *enrichers) list() []garden.Enricher {
+	var list []garden.Enricher
+	for _, enricher := range []garden.Enricher{e.concourse, e.cfEnricher} {
+		if enricher != nil {
+			list = append(list, enricher)
+		}
+	}
+	return list
+}
+
+// closeUnshared stops the enrichers that other does not reuse.
+func (e *enrichers) closeUnshared(other *enrichers) {
+	if e.closeATC != nil && (other == nil || e.concourse != other.concourse) {
+		e.closeATC()
+	}
+}
+
+// server serves the plugin and reconfigures it when the configuration is
+// reloaded. The plugin itself is kept, so that the Scope socket stays open
+// and the history and counters collected from Garden are not lost. It also
+// serves the metrics listener, which moves when its address changes.
+type server struct {
+	lock        sync.Mutex
+	plugin      plugin
+	enrichers   *enrichers
+	metrics     *http.Server
+	metricsAddr string
+}
+
+func newServer(c config) (*server, error) {
+	e := newEnrichers(c, nil)
+
+	p, err := garden.NewPlugin(c.pluginConfig(), e.list()...)
+	if err != nil {
+		e.closeUnshared(nil)
+		return nil, err
+	}
+
+	s := &server{plugin: p, enrichers: e, metricsAddr: c.Metrics.Addr}
+
+	s.metrics, err = s.serveMetrics(c.Metrics.Addr)
+	if err != nil {
+		s.Close()
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// reload rebuilds the enrichers and reconfigures the plugin from c. If the
+// new configuration cannot be applied, the current one is kept.
+func (s *server) reload(c config) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	metrics := s.metrics
+	if c.Metrics.Addr != s.metricsAddr {
+		var err error
+		if metrics, err = s.serveMetrics(c.Metrics.Addr); err != nil {
+			return err
+		}
+	}
+
+	e := newEnrichers(c, s.enrichers)
+
+	if err := s.plugin.Reconfigure(c.pluginConfig(), e.list()...); err != nil {
+		e.closeUnshared(s.enrichers)
+		if metrics != s.metrics && metrics != nil {
+			metrics.Close()
+		}
+		return err
+	}
+
+	s.enrichers.closeUnshared(e)
+	s.enrichers = e
+
+	if metrics != s.metrics && s.metrics != nil {
+		s.metrics.Close()
+	}
+	s.metrics, s.metricsAddr = metrics, c.Metrics.Addr
+
+	return nil
+}
+
+// serveMetrics serves Prometheus metrics on a TCP listener of their own, so
+// that they can be scraped without access to the Scope socket. It returns
+// nil if addr is empty.
+func (s *server) serveMetrics(addr string) (*http.Server, error) {
+	if addr == "" {
+		return nil, nil
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("error listening on %q: %v", addr, err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", s.Metrics)
+	metrics := &http.Server{Handler: mux}
+
+	log.Printf("Serving metrics on http://%s/metrics\n", listener.Addr())
+
+	go func() {
+		if err := metrics.Serve(listener); err != http.ErrServerClosed {
+			log.Printf("error serving metrics: %v\n", err)
+		}
+	}()
+
+	return metrics, nil
+}
+
+func (s *server) Close() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.metrics != nil {
+		s.metrics.Close()
+	}
+	s.plugin.Close()
+	s.enrichers.closeUnshared(nil)
+}
+
+func (s *server) Report(w http.ResponseWriter, r *http.Request) {
+	s.plugin.Report(w, r)
+}
+
+func (s *server) Control(w http.ResponseWriter, r *http.Request) {
+	s.plugin.Control(w, r)
+}
+
+func (s *server) Healthz(w http.ResponseWriter, r *http.Request) {
+	s.plugin.Healthz(w, r)
+}
+
+func (s *server) Metrics(w http.ResponseWriter, r *http.Request) {
+	s.plugin.Metrics(w, r)
+}
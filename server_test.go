@@ -0,0 +1,81 @@
+package main
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("server", func() {
+	var c config
+
+	BeforeEach(func() {
+		var err error
+		c, err = loadConfig("")
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("keeps the plugin and replaces its enrichers on reload", func() {
+		s, err := newServer(c)
+		Expect(err).ToNot(HaveOccurred())
+		defer s.Close()
+
+		p, old := s.plugin, s.enrichers
+		c.CF.Enabled = true
+		Expect(s.reload(c)).To(Succeed())
+		Expect(s.plugin).To(BeIdenticalTo(p))
+		Expect(s.enrichers).ToNot(BeIdenticalTo(old))
+		Expect(s.enrichers.list()).To(HaveLen(1))
+	})
+
+	It("reuses enrichers whose settings did not change", func() {
+		c.ATC.URL = "http://127.0.0.1:1"
+		c.CF.Enabled = true
+		s, err := newServer(c)
+		Expect(err).ToNot(HaveOccurred())
+		defer s.Close()
+
+		concourse, cf := s.enrichers.concourse, s.enrichers.cfEnricher
+		Expect(concourse).ToNot(BeNil())
+
+		c.Garden.Workers++
+		c.Garden.Properties.Deny = []string{"network.*"}
+		Expect(s.reload(c)).To(Succeed())
+		Expect(s.enrichers.concourse).To(BeIdenticalTo(concourse))
+		Expect(s.enrichers.cfEnricher).To(BeIdenticalTo(cf))
+
+		c.ATC.Teams = []string{"main"}
+		Expect(s.reload(c)).To(Succeed())
+		Expect(s.enrichers.concourse).ToNot(BeIdenticalTo(concourse))
+		Expect(s.enrichers.cfEnricher).To(BeIdenticalTo(cf))
+	})
+
+	It("keeps the current enrichers if the new property rules are invalid", func() {
+		s, err := newServer(c)
+		Expect(err).ToNot(HaveOccurred())
+		defer s.Close()
+
+		old := s.enrichers
+		c.Garden.Properties.Allow = []string{"[a-"}
+		Expect(s.reload(c)).ToNot(Succeed())
+		Expect(s.enrichers).To(BeIdenticalTo(old))
+	})
+
+	It("moves the metrics listener on reload", func() {
+		c.Metrics.Addr = "127.0.0.1:0"
+		s, err := newServer(c)
+		Expect(err).ToNot(HaveOccurred())
+		defer s.Close()
+
+		Expect(s.metrics).ToNot(BeNil())
+		metrics := s.metrics
+
+		c.Metrics.Addr = "256.0.0.1:0"
+		Expect(s.reload(c)).ToNot(Succeed())
+		Expect(s.metrics).To(BeIdenticalTo(metrics))
+		Expect(s.metricsAddr).To(Equal("127.0.0.1:0"))
+
+		c.Metrics.Addr = ""
+		Expect(s.reload(c)).To(Succeed())
+		Expect(s.metrics).To(BeNil())
+	})
+})